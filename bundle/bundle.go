@@ -0,0 +1,283 @@
+// Package bundle implements offline provisioning bundles, tarballs containing the packages,
+// box binary and docker images needed to provision hosts without internet access, along
+// with a manifest listing the checksums of all included files.
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// errors
+var (
+	ErrNoManifest       = errors.New("Bundle has no manifest")
+	ErrChecksumMismatch = errors.New("Bundle file does not match checksum")
+	ErrMissingFile      = errors.New("Bundle is missing file listed in manifest")
+	ErrUnexpectedFile   = errors.New("Bundle contains file not listed in manifest")
+	ErrInvalidFileName  = errors.New("Bundle file name is invalid")
+	ErrUnknownDistro    = errors.New("Unable to determine package manager of distro image")
+	ErrHostMismatch     = errors.New("Bundle does not match host")
+)
+
+// ManifestFile defines the name of the manifest within a bundle, it is always the first
+// file of the tarball.
+const ManifestFile = "manifest.json"
+
+// kinds of files within a bundle.
+const (
+	PackageKind = "package"
+	BinaryKind  = "binary"
+	ImageKind   = "image"
+)
+
+// File contains the details of a file within a bundle.
+type File struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the contents of a bundle and the hosts it's able to provision.
+type Manifest struct {
+	Created time.Time `json:"created"`
+
+	// Distro contains the docker image of the distro the packages were collected from,
+	// like `ubuntu:16.04`.
+	Distro         string `json:"distro"`
+	PackageManager string `json:"package_manager"`
+
+	// Arch contains the architecture of the packages as reported by `uname -m`.
+	Arch string `json:"arch"`
+
+	Packages []string `json:"packages"`
+	Images   []string `json:"images"`
+	Files    []File   `json:"files"`
+}
+
+// FilesOf returns the files of the giving kind.
+func (m Manifest) FilesOf(kind string) []File {
+	var files []File
+	for _, file := range m.Files {
+		if file.Kind == kind {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+//===============================================================================================================
+
+// Builder collects files from the local filesystem into a bundle.
+type Builder struct {
+	Manifest Manifest
+	paths    map[string]string
+}
+
+// NewBuilder returns a new Builder for the giving manifest.
+func NewBuilder(manifest Manifest) *Builder {
+	return &Builder{Manifest: manifest, paths: map[string]string{}}
+}
+
+// Add adds the file at the local path into the bundle as name, computing it's checksum.
+func (b *Builder) Add(kind string, name string, localPath string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+
+	b.paths[name] = localPath
+	b.Manifest.Files = append(b.Manifest.Files, File{
+		Name:   name,
+		Kind:   kind,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+
+	return nil
+}
+
+// Write writes the bundle as a tarball into the writer, starting with it's manifest.
+func (b *Builder) Write(w io.Writer) error {
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ManifestFile,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: b.Manifest.Created,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, file := range b.Manifest.Files {
+		if err := b.writeFile(tw, file); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func (b *Builder) writeFile(tw *tar.Writer, file File) error {
+	local, err := os.Open(b.paths[file.Name])
+	if err != nil {
+		return err
+	}
+
+	defer local.Close()
+
+	mode := int64(0644)
+	if file.Kind == BinaryKind {
+		mode = 0755
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    file.Name,
+		Mode:    mode,
+		Size:    file.Size,
+		ModTime: b.Manifest.Created,
+	}); err != nil {
+		return err
+	}
+
+	// Files changed after being added would corrupt the tarball, so copy only the
+	// recorded size and let the checksum catch modified contents.
+	_, err = io.CopyN(tw, local, file.Size)
+	return err
+}
+
+//===============================================================================================================
+
+// Bundle provides access to the manifest and files of a bundle tarball.
+type Bundle struct {
+	Manifest Manifest
+	Path     string
+}
+
+// Open returns the Bundle at the giving path after verifying all files against the
+// checksums of it's manifest.
+func Open(bundlePath string) (*Bundle, error) {
+	bundle := &Bundle{Path: bundlePath}
+
+	if err := bundle.Walk(func(File, io.Reader) error { return nil }); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Walk calls fn for each file of the bundle in order, with a reader of it's contents.
+// Each file's checksum is verified once fn returns, failing the walk on mismatch.
+func (b *Bundle) Walk(fn func(File, io.Reader) error) error {
+	tarball, err := os.Open(b.Path)
+	if err != nil {
+		return err
+	}
+
+	defer tarball.Close()
+
+	tr := tar.NewReader(tarball)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestFile {
+		return ErrNoManifest
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return err
+	}
+
+	files := map[string]File{}
+	for _, file := range manifest.Files {
+		if err := validName(file.Name); err != nil {
+			return err
+		}
+
+		files[file.Name] = file
+	}
+
+	seen := map[string]bool{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		file, ok := files[header.Name]
+		if !ok {
+			return fmt.Errorf("%s: %q", ErrUnexpectedFile, header.Name)
+		}
+
+		hash := sha256.New()
+		if err := fn(file, io.TeeReader(tr, hash)); err != nil {
+			return err
+		}
+
+		// Drain contents fn did not read so the checksum covers the whole file.
+		if _, err := io.Copy(hash, tr); err != nil {
+			return err
+		}
+
+		if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+			return fmt.Errorf("%s: %q", ErrChecksumMismatch, file.Name)
+		}
+
+		seen[file.Name] = true
+	}
+
+	for _, file := range manifest.Files {
+		if !seen[file.Name] {
+			return fmt.Errorf("%s: %q", ErrMissingFile, file.Name)
+		}
+	}
+
+	b.Manifest = manifest
+	return nil
+}
+
+// validName returns an error if the name is not a clean relative path, preventing files
+// from being extracted outside the bundle's directory.
+func validName(name string) error {
+	if name == "" || name == ManifestFile || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("%s: %q", ErrInvalidFileName, name)
+	}
+
+	return nil
+}
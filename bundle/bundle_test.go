@@ -0,0 +1,268 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/bundle"
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/faux/tests"
+)
+
+func TestBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-bundle")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundlePath := build(dir)
+
+	opened, err := bundle.Open(bundlePath)
+	if err != nil {
+		tests.Failed("Should have succcesfully opened bundle: %+q", err)
+	}
+	tests.Passed("Should have succcesfully opened bundle")
+
+	if len(opened.Manifest.FilesOf(bundle.PackageKind)) != 1 || len(opened.Manifest.FilesOf(bundle.BinaryKind)) != 1 {
+		tests.Failed("Should have listed bundle files in manifest: %+v", opened.Manifest.Files)
+	}
+	tests.Passed("Should have listed bundle files in manifest")
+
+	// Replace the package with different contents of the same size.
+	tampered := filepath.Join(dir, "tampered.tar")
+	rewrite(bundlePath, tampered, "packages/git.deb", "tampered")
+
+	if _, err := bundle.Open(tampered); err == nil {
+		tests.Failed("Should have failed to open tampered bundle")
+	}
+	tests.Passed("Should have failed to open tampered bundle")
+}
+
+func TestProvisioner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-bundle")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	opened, err := bundle.Open(build(dir))
+	if err != nil {
+		tests.Failed("Should have succcesfully opened bundle: %+q", err)
+	}
+
+	sums := map[string]string{}
+	for _, file := range opened.Manifest.Files {
+		sums[file.Name] = file.SHA256
+	}
+
+	expected := []string{
+		exec.PrivilegeCommand,
+		"sh -c 'mkdir -p /var/lib/box/bundle/packages && cat > /var/lib/box/bundle/packages/git.deb.box-tmp && echo '\\''" + sums["packages/git.deb"] + "  /var/lib/box/bundle/packages/git.deb.box-tmp'\\'' | sha256sum -c - >/dev/null || { rm -f /var/lib/box/bundle/packages/git.deb.box-tmp; exit 1; } && chmod 0644 /var/lib/box/bundle/packages/git.deb.box-tmp && mv -f /var/lib/box/bundle/packages/git.deb.box-tmp /var/lib/box/bundle/packages/git.deb'",
+		"sh -c 'mkdir -p /var/lib/box/bundle/bin && cat > /var/lib/box/bundle/bin/box.box-tmp && echo '\\''" + sums["bin/box"] + "  /var/lib/box/bundle/bin/box.box-tmp'\\'' | sha256sum -c - >/dev/null || { rm -f /var/lib/box/bundle/bin/box.box-tmp; exit 1; } && chmod 0644 /var/lib/box/bundle/bin/box.box-tmp && mv -f /var/lib/box/bundle/bin/box.box-tmp /var/lib/box/bundle/bin/box'",
+		"dpkg -i /var/lib/box/bundle/packages/git.deb",
+		"systemctl enable --now docker",
+		"install -m 0755 /var/lib/box/bundle/bin/box /usr/local/bin/box",
+	}
+
+	executor := exectest.New().InOrder()
+	executor.Expect(exec.PrivilegeCommand).Stdout("root")
+	for _, command := range expected[1:] {
+		executor.Expect(command)
+	}
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	provisioner := bundle.Provisioner{
+		Bundle: opened,
+		Facts:  &facts.Facts{PackageManager: "apt-get", Arch: "x86_64", InitSystem: facts.Systemd, OS: &osinfo.Info{ID: "ubuntu", VersionID: "16.04"}},
+	}
+
+	if err := provisioner.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+
+	mismatched := bundle.Provisioner{
+		Bundle: opened,
+		Facts:  &facts.Facts{PackageManager: "apt-get", Arch: "aarch64"},
+	}
+
+	if err := mismatched.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to provision host of other architecture")
+	}
+	tests.Passed("Should have failed to provision host of other architecture")
+
+	mismatched.Facts = &facts.Facts{PackageManager: "apt-get", Arch: "x86_64", OS: &osinfo.Info{ID: "ubuntu", VersionID: "22.04", VersionCodename: "jammy"}}
+	if err := mismatched.Exec(ctx); err == nil || !strings.Contains(err.Error(), bundle.ErrHostMismatch.Error()) {
+		tests.Failed("Should have failed to provision host of other release: %+q", err)
+	}
+	tests.Passed("Should have failed to provision host of other release")
+}
+
+func TestMatchesDistro(t *testing.T) {
+	jammy := &osinfo.Info{ID: "ubuntu", VersionID: "22.04", VersionCodename: "jammy"}
+	alpine := &osinfo.Info{ID: "alpine", VersionID: "3.18.4"}
+	rocky := &osinfo.Info{ID: "rocky", VersionID: "9.3"}
+
+	for _, match := range []struct {
+		image   string
+		info    *osinfo.Info
+		matches bool
+	}{
+		{"ubuntu:22.04", jammy, true},
+		{"ubuntu:jammy", jammy, true},
+		{"ubuntu", jammy, true},
+		{"ubuntu:16.04", jammy, false},
+		{"debian:12", jammy, false},
+		{"docker.io/alpine:3.18", alpine, true},
+		{"alpine:3.1", alpine, false},
+		{"rockylinux:9", rocky, true},
+		{"ubuntu:22.04", nil, false},
+	} {
+		if bundle.MatchesDistro(match.image, match.info) != match.matches {
+			tests.Failed("Should have matched %q against %+v as %t", match.image, match.info, match.matches)
+		}
+	}
+	tests.Passed("Should have matched distro images against host releases")
+}
+
+func TestInstallCommand(t *testing.T) {
+	command := bundle.InstallCommand("apt-get", "sudo ", []string{"/var/lib/box/bundle/packages/git.deb", "/var/lib/box/bundle/packages/curl; reboot.deb"})
+	if command != "sudo dpkg -i /var/lib/box/bundle/packages/git.deb '/var/lib/box/bundle/packages/curl; reboot.deb'" {
+		tests.Failed("Should have quoted package files: %q", command)
+	}
+	tests.Passed("Should have quoted package files")
+}
+
+func TestPackageManagerOf(t *testing.T) {
+	for image, expected := range map[string]string{
+		"ubuntu:16.04":         "apt-get",
+		"library/debian:10":    "apt-get",
+		"centos:7":             "yum",
+		"centos:8":             "dnf",
+		"fedora":               "dnf",
+		"alpine:3.18":          "apk",
+		"archlinux:latest":     "pacman",
+		"docker.io/alpine:3.6": "apk",
+	} {
+		manager, err := bundle.PackageManagerOf(image)
+		if err != nil || manager != expected {
+			tests.Failed("Should have found %q for %q: %q %+q", expected, image, manager, err)
+		}
+	}
+	tests.Passed("Should have found package manager of distro images")
+
+	if _, err := bundle.PackageManagerOf("gentoo"); err == nil {
+		tests.Failed("Should have failed to find package manager of unknown distro")
+	}
+	tests.Passed("Should have failed to find package manager of unknown distro")
+}
+
+// build writes a bundle with a package and box binary into the directory, returning
+// it's path.
+func build(dir string) string {
+	pkg := filepath.Join(dir, "git.deb")
+	binary := filepath.Join(dir, "box")
+
+	for file, content := range map[string]string{pkg: "git-deb!", binary: "#!/bin/sh"} {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			tests.Failed("Should have succcesfully written file: %+q", err)
+		}
+	}
+
+	builder := bundle.NewBuilder(bundle.Manifest{
+		Created:        time.Now(),
+		Distro:         "ubuntu:16.04",
+		PackageManager: "apt-get",
+		Arch:           "x86_64",
+		Packages:       []string{"git"},
+	})
+
+	if err := builder.Add(bundle.PackageKind, "packages/git.deb", pkg); err != nil {
+		tests.Failed("Should have succcesfully added package: %+q", err)
+	}
+
+	if err := builder.Add(bundle.BinaryKind, "bin/box", binary); err != nil {
+		tests.Failed("Should have succcesfully added binary: %+q", err)
+	}
+
+	bundlePath := filepath.Join(dir, "bundle.tar")
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		tests.Failed("Should have succcesfully created bundle: %+q", err)
+	}
+	defer out.Close()
+
+	if err := builder.Write(out); err != nil {
+		tests.Failed("Should have succcesfully written bundle: %+q", err)
+	}
+
+	return bundlePath
+}
+
+// rewrite copies the bundle tarball into target, replacing the contents of the named file.
+func rewrite(source string, target string, name string, content string) {
+	in, err := os.Open(source)
+	if err != nil {
+		tests.Failed("Should have succcesfully opened bundle: %+q", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		tests.Failed("Should have succcesfully created bundle: %+q", err)
+	}
+	defer out.Close()
+
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			tests.Failed("Should have succcesfully read bundle: %+q", err)
+		}
+
+		var body io.Reader = tr
+		if header.Name == name {
+			header.Size = int64(len(content))
+			body = strings.NewReader(content)
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			tests.Failed("Should have succcesfully written header: %+q", err)
+		}
+
+		if _, err := io.Copy(tw, body); err != nil {
+			tests.Failed("Should have succcesfully written file: %+q", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		tests.Failed("Should have succcesfully written bundle: %+q", err)
+	}
+}
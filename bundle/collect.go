@@ -0,0 +1,214 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/linux/alpine"
+	"github.com/influx6/faux/context"
+)
+
+// CollectDir defines the directory within the collecting container packages are copied into.
+const CollectDir = "/bundle"
+
+// DefaultPackages contains the packages collected for each package manager, which include
+// docker and the tools installed by the online provisioners.
+var DefaultPackages = map[string][]string{
+	"apt-get": {"git", "curl", "wget", "ca-certificates", "docker.io"},
+	"dnf":     {"git", "curl", "wget", "docker-ce", "docker-ce-cli", "containerd.io"},
+	"yum":     {"git", "curl", "wget", "docker-ce", "docker-ce-cli", "containerd.io"},
+	"apk":     {"git", "curl", "wget", "docker", "docker-openrc"},
+	"pacman":  {"git", "curl", "wget", "docker"},
+}
+
+// packageManagers maps the images of distros to their package managers.
+var packageManagers = map[string]string{
+	"ubuntu":     "apt-get",
+	"debian":     "apt-get",
+	"raspbian":   "apt-get",
+	"fedora":     "dnf",
+	"rockylinux": "dnf",
+	"almalinux":  "dnf",
+	"centos":     "yum",
+	"alpine":     "apk",
+	"archlinux":  "pacman",
+}
+
+// distroIDs maps the images of distros to the ID reported by their os-release, where
+// they differ.
+var distroIDs = map[string]string{
+	"archlinux":  "arch",
+	"rockylinux": "rocky",
+}
+
+// platforms maps architectures as reported by `uname -m` to docker platforms.
+var platforms = map[string]string{
+	"x86_64":  "linux/amd64",
+	"aarch64": "linux/arm64",
+	"armv7l":  "linux/arm/v7",
+	"i686":    "linux/386",
+	"ppc64le": "linux/ppc64le",
+	"s390x":   "linux/s390x",
+}
+
+// Platform returns the docker platform, like `linux/amd64`, of the architecture as
+// reported by `uname -m`, or an empty string if unknown.
+func Platform(arch string) string {
+	return platforms[arch]
+}
+
+// Arch returns the architecture as reported by `uname -m` of the go architecture, like
+// `amd64`, or an empty string if unknown.
+func Arch(goarch string) string {
+	for arch, platform := range platforms {
+		if strings.Split(platform, "/")[1] == goarch {
+			return arch
+		}
+	}
+
+	return ""
+}
+
+// PackageManagerOf returns the package manager of the distro image, like `ubuntu:16.04`.
+// CentOS images from 8 onwards use dnf.
+func PackageManagerOf(image string) (string, error) {
+	name, tag := imageName(image)
+
+	manager, ok := packageManagers[name]
+	if !ok {
+		return "", fmt.Errorf("%s: %q", ErrUnknownDistro, image)
+	}
+
+	if name == "centos" && tag != "" && tag[0] >= '8' && tag[0] <= '9' {
+		return "dnf", nil
+	}
+
+	return manager, nil
+}
+
+// imageName returns the name of the distro image without it's registry or repository,
+// and it's tag.
+func imageName(image string) (string, string) {
+	name, tag := image, ""
+	if index := strings.LastIndex(image, ":"); index > strings.LastIndex(image, "/") {
+		name, tag = image[:index], image[index+1:]
+	}
+
+	return name[strings.LastIndex(name, "/")+1:], tag
+}
+
+// CollectScript returns the script run as root within a container of the distro image,
+// which downloads the packages and their dependencies into CollectDir.
+func CollectScript(image string, manager string, pkgs []string) string {
+	names := strings.Join(pkgs, " ")
+
+	switch manager {
+	case "apt-get":
+		return fmt.Sprintf("apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y --download-only --no-install-recommends %s && cp /var/cache/apt/archives/*.deb %s/", names, CollectDir)
+	case "dnf":
+		repo := dockerRepo(image)
+		return fmt.Sprintf("dnf install -y dnf-plugins-core && (dnf config-manager --add-repo %s || dnf config-manager addrepo --from-repofile=%s) && dnf install -y --downloadonly --downloaddir=%s %s", repo, repo, CollectDir, names)
+	case "yum":
+		return fmt.Sprintf("yum install -y yum-utils && yum-config-manager --add-repo %s && yum install -y --downloadonly --downloaddir=%s %s", dockerRepo(image), CollectDir, names)
+	case "apk":
+		return fmt.Sprintf("%s && apk update && apk fetch -R -o %s %s", alpine.CommunityCommand(""), CollectDir, names)
+	case "pacman":
+		return fmt.Sprintf("pacman -Syw --noconfirm --cachedir %s %s", CollectDir, names)
+	}
+
+	return ""
+}
+
+// dockerRepo returns the url of docker's rpm repository for the distro image.
+func dockerRepo(image string) string {
+	distro := "centos"
+	if strings.HasPrefix(image, "fedora") {
+		distro = "fedora"
+	}
+
+	return fmt.Sprintf("https://download.docker.com/linux/%s/docker-ce.repo", distro)
+}
+
+// Collector implements the ops.Op interface, downloading packages for a distro into a local
+// directory by running it's package manager within a docker container of the distro.
+type Collector struct {
+	Image          string
+	PackageManager string
+	Packages       []string
+
+	// Platform sets the platform of the container, like `linux/arm64`, allowing packages
+	// to be collected for other architectures.
+	Platform string
+
+	// Dir sets the local directory the packages are downloaded into.
+	Dir string
+
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for collecting the packages.
+func (c Collector) Exec(ctx context.CancelContext) error {
+	dir, err := filepath.Abs(c.Dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	args := []string{"docker", "run", "--rm", "-v", dir + ":" + CollectDir}
+	if c.Platform != "" {
+		args = append(args, "--platform", c.Platform)
+	}
+
+	args = append(args, c.Image, "sh", "-c", CollectScript(c.Image, c.PackageManager, c.Packages))
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, "'"+strings.Replace(arg, "'", `'\''`, -1)+"'")
+	}
+
+	// Packages are collected on the local machine even when the default executor targets
+	// a remote host.
+	cmd := exec.New(exec.Command(strings.Join(quoted, " ")), exec.Async(), exec.Using(exec.LocalExecutor{}))
+
+	if c.DoWithCmd != nil {
+		c.DoWithCmd(cmd)
+	}
+
+	return cmd.Exec(ctx)
+}
+
+// PackageFiles returns the package files within the directory, matching the extension of
+// the package manager's packages.
+func PackageFiles(dir string, manager string) ([]string, error) {
+	pattern := "*.deb"
+
+	switch manager {
+	case "dnf", "yum":
+		pattern = "*.rpm"
+	case "apk":
+		pattern = "*.apk"
+	case "pacman":
+		pattern = "*.pkg.tar*"
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	// pacman keeps package signatures next to packages in it's cache.
+	files := matches[:0]
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".sig") {
+			files = append(files, match)
+		}
+	}
+
+	return files, nil
+}
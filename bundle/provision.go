@@ -0,0 +1,186 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/faux/context"
+)
+
+// HostDir defines the directory on the host the files of a bundle are copied into.
+const HostDir = "/var/lib/box/bundle"
+
+// BinaryPath defines the path on the host the box binary of a bundle is installed into.
+const BinaryPath = "/usr/local/bin/box"
+
+// Provisioner implements the ops.Op interface, provisioning a host entirely from the
+// files of a bundle without requiring network access.
+type Provisioner struct {
+	Bundle *Bundle
+
+	// Facts contains the facts of the host, they are collected if not set.
+	Facts *facts.Facts
+
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for provisioning the host from the bundle.
+func (p Provisioner) Exec(ctx context.CancelContext) error {
+	hostFacts := p.Facts
+	if hostFacts == nil {
+		collected, err := facts.Collect(ctx)
+		if err != nil {
+			return err
+		}
+
+		hostFacts = collected
+	}
+
+	manifest := p.Bundle.Manifest
+
+	if hostFacts.PackageManager != manifest.PackageManager {
+		return fmt.Errorf("%s: bundle packages are for %q, host uses %q", ErrHostMismatch, manifest.PackageManager, hostFacts.PackageManager)
+	}
+
+	if hostFacts.Arch != manifest.Arch {
+		return fmt.Errorf("%s: bundle packages are for %q, host is %q", ErrHostMismatch, manifest.Arch, hostFacts.Arch)
+	}
+
+	if !MatchesDistro(manifest.Distro, hostFacts.OS) {
+		return fmt.Errorf("%s: bundle packages are for %q, host runs %q", ErrHostMismatch, manifest.Distro, hostDistro(hostFacts.OS))
+	}
+
+	privilege, err := exec.Privilege(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Copy the files of the bundle onto the host, verifying each against it's checksum
+	// before it's moved into place.
+	if err := p.Bundle.Walk(func(file File, r io.Reader) error {
+		return exec.WriteFileFromChecked(ctx, path.Join(HostDir, file.Name), r, 0644, privilege, file.SHA256)
+	}); err != nil {
+		return err
+	}
+
+	var commands []string
+
+	if pkgs := manifest.FilesOf(PackageKind); len(pkgs) != 0 {
+		commands = append(commands, InstallCommand(manifest.PackageManager, privilege, hostPaths(pkgs)))
+	}
+
+	commands = append(commands, ServiceCommands(hostFacts.InitSystem, privilege, "docker")...)
+
+	for _, image := range manifest.FilesOf(ImageKind) {
+		commands = append(commands, fmt.Sprintf("%sdocker load -i %s", privilege, exec.QuotePath(path.Join(HostDir, image.Name))))
+	}
+
+	for _, binary := range manifest.FilesOf(BinaryKind) {
+		if path.Base(binary.Name) == "box" {
+			commands = append(commands, fmt.Sprintf("%sinstall -m 0755 %s %s", privilege, exec.QuotePath(path.Join(HostDir, binary.Name)), BinaryPath))
+		}
+	}
+
+	for _, command := range commands {
+		cmd := exec.New(exec.Command(command), exec.Async())
+
+		if p.DoWithCmd != nil {
+			p.DoWithCmd(cmd)
+		}
+
+		if err := cmd.Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InstallCommand returns the command installing the package files with the package
+// manager, without it fetching any packages from it's repositories.
+func InstallCommand(manager string, privilege string, files []string) string {
+	quoted := make([]string, 0, len(files))
+	for _, file := range files {
+		quoted = append(quoted, exec.QuotePath(file))
+	}
+
+	list := strings.Join(quoted, " ")
+
+	switch manager {
+	case "dnf", "yum":
+		return fmt.Sprintf("%s%s install -y --disablerepo='*' %s", privilege, manager, list)
+	case "apk":
+		return fmt.Sprintf("%sapk add --no-network %s", privilege, list)
+	case "pacman":
+		return fmt.Sprintf("%spacman -U --noconfirm --needed %s", privilege, list)
+	}
+
+	return fmt.Sprintf("%sdpkg -i %s", privilege, list)
+}
+
+// ServiceCommands returns the commands enabling and starting the service with the host's
+// init system.
+func ServiceCommands(initSystem string, privilege string, service string) []string {
+	switch initSystem {
+	case facts.Systemd:
+		return []string{fmt.Sprintf("%ssystemctl enable --now %s", privilege, service)}
+	case facts.OpenRC:
+		return []string{
+			fmt.Sprintf("%src-update add %s boot", privilege, service),
+			fmt.Sprintf("%src-service %s start", privilege, service),
+		}
+	}
+
+	return []string{fmt.Sprintf("%sservice %s start", privilege, service)}
+}
+
+// hostPaths returns the paths of the files on the host.
+func hostPaths(files []File) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, path.Join(HostDir, file.Name))
+	}
+
+	return paths
+}
+
+// MatchesDistro returns true if the host's os-release details match the distro image the
+// packages of a bundle were collected from. Images tagged with a version match hosts whose
+// VERSION_ID starts with it, like `alpine:3.18` matching 3.18.4, and images tagged with a
+// codename match hosts of that release. Untagged images and those tagged latest match any
+// release of the distro.
+func MatchesDistro(image string, info *osinfo.Info) bool {
+	if info == nil {
+		return false
+	}
+
+	name, tag := imageName(image)
+	if id, ok := distroIDs[name]; ok {
+		name = id
+	}
+
+	if info.ID != name {
+		return false
+	}
+
+	switch tag {
+	case "", "latest", info.VersionID, info.VersionCodename:
+		return true
+	}
+
+	return strings.HasPrefix(info.VersionID, tag+".")
+}
+
+// hostDistro returns the distro and release of the host for reporting mismatches.
+func hostDistro(info *osinfo.Info) string {
+	if info == nil {
+		return "unknown"
+	}
+
+	return strings.TrimSpace(info.ID + " " + info.VersionID)
+}
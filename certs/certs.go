@@ -0,0 +1,268 @@
+// Package certs implements a certificate authority for securing the docker API of remote
+// hosts with mutual TLS, issuing server certificates for the hosts and client certificates
+// for box. Certificates and keys are stored PEM encoded using the file names docker
+// expects within DOCKER_CERT_PATH.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// errors
+var (
+	ErrInvalidPEM    = errors.New("File does not contain a PEM encoded block")
+	ErrNotCA         = errors.New("Certificate is not a certificate authority")
+	ErrNoSubjectName = errors.New("Server certificate requires at least one IP address or DNS name")
+)
+
+// file names of certificates and keys within a directory, matching those docker expects.
+const (
+	CAFile   = "ca.pem"
+	CAKey    = "ca-key.pem"
+	CertFile = "cert.pem"
+	KeyFile  = "key.pem"
+)
+
+// validity periods of issued certificates.
+const (
+	CAValidity   = 10 * 365 * 24 * time.Hour
+	CertValidity = 2 * 365 * 24 * time.Hour
+)
+
+// Pair contains a certificate and it's private key.
+type Pair struct {
+	Cert *x509.Certificate
+	Key  *ecdsa.PrivateKey
+}
+
+// CertPEM returns the certificate PEM encoded.
+func (p *Pair) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.Cert.Raw})
+}
+
+// KeyPEM returns the private key PEM encoded.
+func (p *Pair) KeyPEM() ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(p.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// Save writes the certificate and key into the files within the directory, the key
+// is only readable by the current user.
+func (p *Pair) Save(dir string, certFile string, keyFile string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	key, err := p.KeyPEM()
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, keyFile), key, 0600); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, certFile), p.CertPEM(), 0644)
+}
+
+// Load returns the Pair stored in the files within the directory.
+func Load(dir string, certFile string, keyFile string) (*Pair, error) {
+	certData, err := ioutil.ReadFile(filepath.Join(dir, certFile))
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, err := ioutil.ReadFile(filepath.Join(dir, keyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certData)
+	if certBlock == nil {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidPEM, certFile)
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyData)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidPEM, keyFile)
+	}
+
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{Cert: cert, Key: key}, nil
+}
+
+//===============================================================================================================
+
+// NewCA returns a new self signed certificate authority.
+func NewCA(commonName string) (*Pair, error) {
+	template, err := newTemplate(commonName, CAValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	return sign(template, nil)
+}
+
+// LoadOrCreateCA returns the certificate authority stored within the directory, creating
+// it if the directory contains none.
+func LoadOrCreateCA(dir string, commonName string) (*Pair, error) {
+	ca, err := Load(dir, CAFile, CAKey)
+	if err == nil {
+		if !ca.Cert.IsCA {
+			return nil, ErrNotCA
+		}
+
+		return ca, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if ca, err = NewCA(commonName); err != nil {
+		return nil, err
+	}
+
+	if err := ca.Save(dir, CAFile, CAKey); err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+// IssueServer returns a new server certificate signed by the certificate authority, valid
+// for the giving IP addresses and DNS names.
+func (p *Pair) IssueServer(commonName string, ips []net.IP, dnsNames []string) (*Pair, error) {
+	if len(ips) == 0 && len(dnsNames) == 0 {
+		return nil, ErrNoSubjectName
+	}
+
+	template, err := newTemplate(commonName, CertValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IPAddresses = ips
+	template.DNSNames = dnsNames
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	return sign(template, p)
+}
+
+// IssueClient returns a new client certificate signed by the certificate authority.
+func (p *Pair) IssueClient(commonName string) (*Pair, error) {
+	template, err := newTemplate(commonName, CertValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	return sign(template, p)
+}
+
+// newTemplate returns a certificate template with a random serial number, valid from
+// now for the validity period.
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow for clocks of hosts running slightly behind.
+	notBefore := time.Now().Add(-time.Hour).UTC()
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"box"}},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(validity),
+	}, nil
+}
+
+// sign generates a key for the template and signs it with the certificate authority, or
+// self signs it if ca is nil.
+func sign(template *x509.Certificate, ca *Pair) (*Pair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.Cert, ca.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{Cert: cert, Key: key}, nil
+}
+
+//===============================================================================================================
+
+// ClientConfig returns a tls.Config for connecting to docker hosts using the client
+// certificate within the directory, trusting only the certificate authority stored
+// alongside it.
+func ClientConfig(dir string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, CertFile), filepath.Join(dir, KeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	caData, err := ioutil.ReadFile(filepath.Join(dir, CAFile))
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidPEM, CAFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package certs_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influx6/box/certs"
+	"github.com/influx6/faux/tests"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-certs")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	created, err := certs.LoadOrCreateCA(dir, "box")
+	if err != nil {
+		tests.Failed("Should have succcesfully created CA: %+q", err)
+	}
+	tests.Passed("Should have succcesfully created CA")
+
+	loaded, err := certs.LoadOrCreateCA(dir, "box")
+	if err != nil {
+		tests.Failed("Should have succcesfully loaded CA: %+q", err)
+	}
+
+	if loaded.Cert.SerialNumber.Cmp(created.Cert.SerialNumber) != 0 {
+		tests.Failed("Should have loaded the created CA")
+	}
+	tests.Passed("Should have loaded the created CA")
+
+	stat, err := os.Stat(filepath.Join(dir, certs.CAKey))
+	if err != nil || stat.Mode().Perm() != 0600 {
+		tests.Failed("Should have saved CA key readable only by owner: %+q", err)
+	}
+	tests.Passed("Should have saved CA key readable only by owner")
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-certs")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := certs.NewCA("box")
+	if err != nil {
+		tests.Failed("Should have succcesfully created CA: %+q", err)
+	}
+
+	if _, err := ca.IssueServer("docker", nil, nil); err == nil {
+		tests.Failed("Should have failed to issue server certificate without names")
+	}
+	tests.Passed("Should have failed to issue server certificate without names")
+
+	server, err := ca.IssueServer("docker", []net.IP{net.ParseIP("127.0.0.1")}, []string{"localhost"})
+	if err != nil {
+		tests.Failed("Should have succcesfully issued server certificate: %+q", err)
+	}
+
+	client, err := ca.IssueClient("box")
+	if err != nil {
+		tests.Failed("Should have succcesfully issued client certificate: %+q", err)
+	}
+
+	if err := client.Save(dir, certs.CertFile, certs.KeyFile); err != nil {
+		tests.Failed("Should have succcesfully saved client certificate: %+q", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, certs.CAFile), ca.CertPEM(), 0644); err != nil {
+		tests.Failed("Should have succcesfully saved CA certificate: %+q", err)
+	}
+
+	serverKey, err := server.KeyPEM()
+	if err != nil {
+		tests.Failed("Should have succcesfully encoded server key: %+q", err)
+	}
+
+	serverCert, err := tls.X509KeyPair(server.CertPEM(), serverKey)
+	if err != nil {
+		tests.Failed("Should have succcesfully loaded server certificate: %+q", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		tests.Failed("Should have succcesfully listened: %+q", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	config, err := certs.ClientConfig(dir)
+	if err != nil {
+		tests.Failed("Should have succcesfully loaded client config: %+q", err)
+	}
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), config)
+	if err != nil {
+		tests.Failed("Should have succcesfully completed mutual TLS handshake: %+q", err)
+	}
+	conn.Close()
+	tests.Passed("Should have succcesfully completed mutual TLS handshake")
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/bundle"
+	"github.com/influx6/box/docker"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+	"github.com/moby/moby/client"
+)
+
+var bundleCommands = []cli.Command{
+	{
+		Name:   "create",
+		Usage:  "Creates a bundle of packages, the box binary and docker images for provisioning hosts without internet access",
+		Action: bundleCreateFn,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "distro, d",
+				Value: "ubuntu:16.04",
+				Usage: "docker image of the distro release the bundled hosts run",
+			},
+			cli.StringFlag{
+				Name:  "arch",
+				Value: bundle.Arch(runtime.GOARCH),
+				Usage: "architecture of the bundled hosts as reported by `uname -m`",
+			},
+			cli.StringFlag{
+				Name:  "package-manager",
+				Usage: "package manager of the distro, detected from the distro image if not set",
+			},
+			cli.StringSliceFlag{
+				Name:  "package, p",
+				Value: &cli.StringSlice{},
+				Usage: "package to bundle in place of the defaults, may be repeated",
+			},
+			cli.StringSliceFlag{
+				Name:  "image, i",
+				Value: &cli.StringSlice{},
+				Usage: "docker image to bundle, may be repeated",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Value: "bundle.tar",
+				Usage: "path the bundle is written to",
+			},
+		},
+	},
+}
+
+// bundleCreateFn defines the action called to create an offline provisioning bundle.
+func bundleCreateFn(c *cli.Context) {
+	if err := createBundle(c); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to create bundle"))
+		return
+	}
+
+	fmt.Println(color.GreenString(fmt.Sprintf("Bundle written to %q", c.String("output"))))
+}
+
+func createBundle(c *cli.Context) error {
+	distro, arch := c.String("distro"), c.String("arch")
+
+	platform := bundle.Platform(arch)
+	if platform == "" {
+		return fmt.Errorf("unknown architecture %q", arch)
+	}
+
+	manager := c.String("package-manager")
+	if manager == "" {
+		detected, err := bundle.PackageManagerOf(distro)
+		if err != nil {
+			return err
+		}
+
+		manager = detected
+	}
+
+	pkgs := c.StringSlice("package")
+	if len(pkgs) == 0 {
+		pkgs = bundle.DefaultPackages[manager]
+	}
+
+	dir, err := ioutil.TempDir("", "box-bundle")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	fmt.Printf("Collecting %s packages from %q\n", manager, distro)
+
+	collector := bundle.Collector{
+		Image:          distro,
+		PackageManager: manager,
+		Packages:       pkgs,
+		Platform:       platform,
+		Dir:            filepath.Join(dir, "packages"),
+	}
+
+	if err := collector.Exec(ctx); err != nil {
+		return err
+	}
+
+	builder := bundle.NewBuilder(bundle.Manifest{
+		Created:        time.Now().UTC(),
+		Distro:         distro,
+		PackageManager: manager,
+		Arch:           arch,
+		Packages:       pkgs,
+		Images:         c.StringSlice("image"),
+	})
+
+	files, err := bundle.PackageFiles(collector.Dir, manager)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := builder.Add(bundle.PackageKind, "packages/"+filepath.Base(file), file); err != nil {
+			return err
+		}
+	}
+
+	// The running binary is only usable on hosts of the same architecture.
+	if arch == bundle.Arch(runtime.GOARCH) && runtime.GOOS == "linux" {
+		binary, err := os.Executable()
+		if err != nil {
+			return err
+		}
+
+		if err := builder.Add(bundle.BinaryKind, "bin/box", binary); err != nil {
+			return err
+		}
+	}
+
+	if images := c.StringSlice("image"); len(images) != 0 {
+		fmt.Printf("Saving docker images %q\n", images)
+
+		imagesFile := filepath.Join(dir, "images.tar")
+		if err := saveImages(ctx, images, imagesFile); err != nil {
+			return err
+		}
+
+		if err := builder.Add(bundle.ImageKind, "images/images.tar", imagesFile); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(c.String("output"))
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	if err := builder.Write(out); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// saveImages saves the docker images as a tarball into the file.
+func saveImages(ctx context.Context, images []string, file string) error {
+	dockerClient, err := client.NewEnvClient()
+	if err != nil {
+		return err
+	}
+
+	save, err := docker.New(dockerClient).ImageSave(images)
+	if err != nil {
+		return err
+	}
+
+	return save.Exec(ctx, func(r io.ReadCloser) error {
+		defer r.Close()
+
+		out, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+
+		defer out.Close()
+
+		if _, err := io.Copy(out, r); err != nil {
+			return err
+		}
+
+		return out.Close()
+	})
+}
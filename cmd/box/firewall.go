@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/firewall"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+)
+
+var firewallFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "host",
+		Usage: "name of a registered host to configure instead of the local machine",
+	},
+	cli.StringSliceFlag{
+		Name:  "allow, a",
+		Value: &cli.StringSlice{},
+		Usage: "port to allow in PORT/PROTO format, may be repeated, defaults to the box and docker TLS ports",
+	},
+	cli.StringSliceFlag{
+		Name:  "remove, r",
+		Value: &cli.StringSlice{},
+		Usage: "port to remove a previously allowed rule for in PORT/PROTO format, may be repeated",
+	},
+	cli.StringFlag{
+		Name:  "from",
+		Usage: "CIDR the ports are allowed from, defaults to anywhere",
+	},
+	cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "only print the changes to the firewall's rules",
+	},
+}
+
+// firewallFn defines the action called to open the ports of box and docker on a host's firewall.
+func firewallFn(c *cli.Context) {
+	if err := configureFirewall(c); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok {
+			events.Emit(metrics.With(logKey, errLog).With("error", cmdErr.Err).WithMessage("Failed to configure firewall:\n%s", cmdErr.Report()))
+			return
+		}
+
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to configure firewall"))
+	}
+}
+
+func configureFirewall(c *cli.Context) error {
+	allowSpecs := c.StringSlice("allow")
+	if len(allowSpecs) == 0 {
+		allowSpecs = []string{strconv.Itoa(firewall.BoxPort) + "/tcp", strconv.Itoa(firewall.DockerTLSPort) + "/tcp"}
+	}
+
+	allow, err := parseRules(allowSpecs, c.String("from"))
+	if err != nil {
+		return err
+	}
+
+	remove, err := parseRules(c.StringSlice("remove"), c.String("from"))
+	if err != nil {
+		return err
+	}
+
+	if name := c.String("host"); name != "" {
+		host, err := findHost(name)
+		if err != nil {
+			return err
+		}
+
+		executor, err := hostExecutor(host)
+		if err != nil {
+			return err
+		}
+
+		defer executor.Close()
+		exec.SetDefaultExecutor(executor)
+		defer exec.SetDefaultExecutor(nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	hostFacts, err := facts.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	privilege, err := exec.Privilege(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Firewall: %s\n", hostFacts.Firewall)
+
+	fw := firewall.Firewall{
+		Backend:   hostFacts.Firewall,
+		Allow:     allow,
+		Remove:    remove,
+		Report:    os.Stdout,
+		DryRun:    c.Bool("dry-run"),
+		Privilege: privilege,
+	}
+
+	return fw.Exec(ctx)
+}
+
+func parseRules(specs []string, from string) ([]firewall.Rule, error) {
+	rules := make([]firewall.Rule, 0, len(specs))
+	for _, spec := range specs {
+		rule, err := firewall.ParseRule(spec, from)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/docker"
+	"github.com/influx6/box/funcs"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+)
+
+var funcFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "name, n",
+		Usage: "name for the image and routes, defaults to the binary's file name",
+	},
+	cli.StringFlag{
+		Name:  "base, b",
+		Value: funcs.DefaultBaseImage,
+		Usage: "base image the binary runs within",
+	},
+	cli.IntFlag{
+		Name:  "port, p",
+		Value: funcs.DefaultPort,
+		Usage: "port the wrapper server listens on",
+	},
+	cli.StringFlag{
+		Name:  "host",
+		Usage: "name of a registered host whose docker builds the image, see `box hosts tls`",
+	},
+	cli.BoolFlag{
+		Name:  "verbose, v",
+		Usage: "print the docker build output",
+	},
+}
+
+// funcFn defines the action called to wrap a binary into a docker image.
+func funcFn(c *cli.Context) {
+	img := funcs.Image{
+		Namespace: c.Args().First(),
+		Binary:    c.Args().Get(1),
+		Name:      c.String("name"),
+		BaseImage: c.String("base"),
+		Port:      c.Int("port"),
+	}
+
+	if err := img.Validate(); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Usage: box func NAMESPACE BINARY"))
+		return
+	}
+
+	apiClient, err := dockerClient(c.String("host"))
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to create docker client"))
+		return
+	}
+
+	tags := img.Tags()
+
+	fmt.Printf("Creating docker image for binary: %q\n", tags[0])
+
+	var progress io.Writer
+	if c.Bool("verbose") {
+		progress = os.Stdout
+	}
+
+	id, err := img.Build(context.Background(), docker.New(apiClient), progress)
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to build docker image for %q", img.Binary))
+		return
+	}
+
+	for _, tag := range tags {
+		fmt.Printf("Tagging docker image as %q\n", tag)
+	}
+
+	fmt.Printf("Using docker host: %s\n", apiClient.DaemonHost())
+	fmt.Println(color.GreenString(fmt.Sprintf("Image %q built with id %s", tags[0], id)))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/hosts"
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/sshd"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+	"golang.org/x/crypto/ssh"
+)
+
+var hardenFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "no-password",
+		Usage: "disable password authentication",
+	},
+	cli.StringFlag{
+		Name:  "root-login",
+		Usage: "how root may login, one of no, prohibit-password or yes",
+	},
+	cli.StringSliceFlag{
+		Name:  "allow-user",
+		Value: &cli.StringSlice{},
+		Usage: "user allowed to login, may be repeated",
+	},
+	cli.IntFlag{
+		Name:  "port",
+		Usage: "port sshd listens on",
+	},
+	cli.StringSliceFlag{
+		Name:  "cipher",
+		Value: &cli.StringSlice{},
+		Usage: "cipher sshd allows, may be repeated",
+	},
+}
+
+// hostsHardenFn defines the action called to harden the ssh server of a registered host.
+func hostsHardenFn(c *cli.Context) {
+	name := c.Args().First()
+
+	if err := hardenHost(c, name); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok {
+			events.Emit(metrics.With(logKey, errLog).With("error", cmdErr.Err).WithMessage("Failed to harden sshd of host %q:\n%s", name, cmdErr.Report()))
+			return
+		}
+
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to harden sshd of host %q", name))
+		return
+	}
+
+	fmt.Println(color.GreenString(fmt.Sprintf("Hardened sshd of host %q", name)))
+}
+
+func hardenHost(c *cli.Context, name string) error {
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		return err
+	}
+
+	host, err := inv.Get(name)
+	if err != nil {
+		return err
+	}
+
+	config := sshd.Config{
+		Port:            c.Int("port"),
+		PermitRootLogin: c.String("root-login"),
+		AllowUsers:      c.StringSlice("allow-user"),
+		Ciphers:         c.StringSlice("cipher"),
+	}
+
+	if c.Bool("no-password") {
+		disabled := false
+		config.PasswordAuthentication = &disabled
+	}
+
+	executor, err := hostExecutor(host)
+	if err != nil {
+		return err
+	}
+
+	defer executor.Close()
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	// The key the connection authenticated with, which may be one of the ssh-agent's
+	// rather than the host's identity, must stay authorized.
+	var keys []ssh.PublicKey
+	if key := executor.AuthKey(); key != nil {
+		keys = append(keys, key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	hostFacts, err := facts.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	privilege, err := exec.Privilege(ctx)
+	if err != nil {
+		return err
+	}
+
+	harden := sshd.Harden{
+		Config:     config,
+		Keys:       keys,
+		InitSystem: hostFacts.InitSystem,
+		Firewall:   hostFacts.Firewall,
+		SELinux:    hostFacts.SELinux,
+		Privilege:  privilege,
+	}
+
+	if err := harden.Exec(ctx); err != nil {
+		return err
+	}
+
+	if config.Port == 0 {
+		return nil
+	}
+
+	// Later connections must use the port sshd now listens on.
+	addr, _, err := net.SplitHostPort(host.Addr)
+	if err != nil {
+		return err
+	}
+
+	host.Addr = net.JoinHostPort(addr, strconv.Itoa(config.Port))
+
+	if err := inv.Update(host); err != nil {
+		return err
+	}
+
+	return inv.Save()
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/hosts"
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+	"golang.org/x/crypto/ssh"
+)
+
+// dialTimeout defines the timeout for connecting to a registered host.
+const dialTimeout = 30 * time.Second
+
+var errHostKeyUnconfirmed = errors.New("Host key was not confirmed, use --fingerprint or --yes to pin it")
+
+var (
+	registerFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "name, n",
+			Usage: "name to register the host with",
+		},
+		cli.StringFlag{
+			Name:  "transport, t",
+			Value: hosts.SSHTransport,
+			Usage: "transport used to reach the host (ssh, local, service)",
+		},
+		cli.StringFlag{
+			Name:  "user, u",
+			Usage: "user to connect to the host as",
+		},
+		cli.StringFlag{
+			Name:  "identity, i",
+			Usage: "private key file used to authenticate with the host",
+		},
+		cli.StringSliceFlag{
+			Name:  "label, l",
+			Value: &cli.StringSlice{},
+			Usage: "label in key=value format to attach to the host",
+		},
+		cli.StringSliceFlag{
+			Name:  "fingerprint, f",
+			Value: &cli.StringSlice{},
+			Usage: "host key fingerprint to pin for the host",
+		},
+		cli.BoolFlag{
+			Name:  "yes, y",
+			Usage: "pin the host key presented by the host without confirmation when it's not listed in ~/.ssh/known_hosts",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "replace the host if it's already registered",
+		},
+	}
+
+	hostsCommands = []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "Lists all registered hosts",
+			Action: hostsListFn,
+		},
+		{
+			Name:      "show",
+			Usage:     "Shows the details of a registered host",
+			ArgsUsage: "NAME",
+			Action:    hostsShowFn,
+		},
+		{
+			Name:      "remove",
+			Usage:     "Removes a registered host",
+			ArgsUsage: "NAME",
+			Action:    hostsRemoveFn,
+		},
+		{
+			Name:      "tls",
+			Usage:     "Secures the docker API of a registered host with TLS client certificates",
+			ArgsUsage: "NAME",
+			Action:    hostsTLSFn,
+			Flags:     tlsFlags,
+		},
+		{
+			Name:      "harden",
+			Usage:     "Hardens the ssh server of a registered host, refusing settings which would lock box out",
+			ArgsUsage: "NAME",
+			Action:    hostsHardenFn,
+			Flags:     hardenFlags,
+		},
+	}
+)
+
+// registerFn defines the action called to add a host into the local inventory.
+func registerFn(c *cli.Context) {
+	addr := c.Args().First()
+	name := c.String("name")
+	if name == "" {
+		name = addr
+	}
+
+	labels, err := hosts.ParseLabels(c.StringSlice("label"))
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to parse labels for %q", name))
+		return
+	}
+
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to load host inventory"))
+		return
+	}
+
+	host := hosts.Host{
+		Name:         name,
+		Addr:         addr,
+		Transport:    c.String("transport"),
+		User:         c.String("user"),
+		Identity:     c.String("identity"),
+		Labels:       labels,
+		Fingerprints: c.StringSlice("fingerprint"),
+	}
+
+	fmt.Printf("Adding service %q as box provider\n", host.Name)
+
+	// Validate the host before connecting to it.
+	if err := host.Validate(); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to register host %q", name))
+		return
+	}
+
+	if _, err := inv.Get(host.Name); err == nil && !c.Bool("force") {
+		events.Emit(metrics.With(logKey, errLog).With("error", hosts.ErrHostExists).WithMessage("Failed to register host %q", name))
+		return
+	}
+
+	if host.Transport == hosts.SSHTransport && len(host.Fingerprints) == 0 {
+		fingerprint, err := hostKeyFingerprint(host.Addr, c.Bool("yes"))
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to verify host key of %q", host.Addr))
+			return
+		}
+
+		fmt.Printf("Negotiate ssh encryption keys with remote box service: %s\n", fingerprint)
+		host.Fingerprints = append(host.Fingerprints, fingerprint)
+	}
+
+	if err := inv.Add(host, c.Bool("force")); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to register host %q", name))
+		return
+	}
+
+	if err := inv.Save(); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to save host inventory %q", inv.Path()))
+		return
+	}
+
+	fmt.Printf("Service registered to remote host %q.\n", host.Addr)
+	fmt.Println(color.GreenString(fmt.Sprintf("Service %q ready for operation. :)", host.Name)))
+}
+
+// hostKeyFingerprint returns the fingerprint of the host key presented by the host at
+// the address, once it's found within ~/.ssh/known_hosts or confirmed by the user.
+func hostKeyFingerprint(addr string, confirmed bool) (string, error) {
+	key, err := exec.HostKey(addr, dialTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	known, err := exec.KnownHostKey(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"), addr, key)
+	if err != nil {
+		return "", err
+	}
+
+	if known || confirmed {
+		return fingerprint, nil
+	}
+
+	fmt.Printf("The host key of %q is not listed in ~/.ssh/known_hosts.\n", addr)
+	fmt.Printf("%s key fingerprint is %s.\n", key.Type(), fingerprint)
+	fmt.Print("Are you sure you want to pin this host key (yes/no)? ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "yes" && answer != "y" {
+		return "", errHostKeyUnconfirmed
+	}
+
+	return fingerprint, nil
+}
+
+// hostsListFn defines the action called to list all registered hosts.
+func hostsListFn(c *cli.Context) {
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to load host inventory"))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tADDRESS\tTRANSPORT\tLABELS")
+
+	for _, host := range inv.List() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", host.Name, host.Addr, host.Transport, formatLabels(host.Labels))
+	}
+
+	tw.Flush()
+}
+
+// hostsShowFn defines the action called to display the details of a registered host.
+func hostsShowFn(c *cli.Context) {
+	host, err := findHost(c.Args().First())
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to find host %q", c.Args().First()))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", host.Name)
+	fmt.Fprintf(tw, "Address:\t%s\n", host.Addr)
+	fmt.Fprintf(tw, "Transport:\t%s\n", host.Transport)
+	fmt.Fprintf(tw, "User:\t%s\n", host.User)
+	fmt.Fprintf(tw, "Identity:\t%s\n", host.Identity)
+	fmt.Fprintf(tw, "Fingerprints:\t%s\n", strings.Join(host.Fingerprints, ", "))
+	fmt.Fprintf(tw, "Labels:\t%s\n", formatLabels(host.Labels))
+	fmt.Fprintf(tw, "Added:\t%s\n", host.Added)
+	fmt.Fprintf(tw, "Docker:\t%s\n", host.DockerAddr)
+	fmt.Fprintf(tw, "Docker Certs:\t%s\n", host.DockerCerts)
+	tw.Flush()
+}
+
+// hostsRemoveFn defines the action called to remove a registered host.
+func hostsRemoveFn(c *cli.Context) {
+	name := c.Args().First()
+
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to load host inventory"))
+		return
+	}
+
+	if err := inv.Remove(name); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to remove host %q", name))
+		return
+	}
+
+	if err := inv.Save(); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to save host inventory %q", inv.Path()))
+		return
+	}
+
+	fmt.Printf("Host %q removed.\n", name)
+}
+
+// hostExecutor returns a SSHExecutor connected to the provided host, authenticating with
+// the host's identity file and the ssh-agent if available.
+func hostExecutor(host hosts.Host) (*exec.SSHExecutor, error) {
+	if host.Transport != hosts.SSHTransport {
+		return nil, hosts.ErrUnknownTransport
+	}
+
+	config := exec.SSHConfig{
+		User:         host.User,
+		Fingerprints: host.Fingerprints,
+		UseAgent:     os.Getenv("SSH_AUTH_SOCK") != "",
+		Timeout:      dialTimeout,
+	}
+
+	if config.User == "" {
+		config.User = os.Getenv("USER")
+	}
+
+	if host.Identity != "" {
+		config.KeyFiles = append(config.KeyFiles, host.Identity)
+	}
+
+	return exec.DialSSH(host.Addr, config)
+}
+
+// findHost returns the host with the giving name from the default inventory.
+func findHost(name string) (hosts.Host, error) {
+	if name == "" {
+		return hosts.Host{}, hosts.ErrInvalidHostName
+	}
+
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		return hosts.Host{}, err
+	}
+
+	return inv.Get(name)
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, val := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -9,8 +10,12 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/influx6/box"
+	"github.com/influx6/box/bundle"
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/linux/users"
 	"github.com/influx6/faux/metrics"
 	"github.com/influx6/faux/metrics/sentries/custom"
+	"github.com/influx6/faux/ops"
 	"github.com/minio/cli"
 
 	_ "github.com/influx6/box/recipes/darwin"
@@ -86,7 +91,59 @@ func main() {
 			Name:        "init",
 			Action:      initFn,
 			Description: "Runs all needed actions to install and provision the host for hosting docker containers",
-			Flags:       []cli.Flag{},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "host",
+					Usage: "name of a registered host to provision instead of the local machine",
+				},
+				cli.StringFlag{
+					Name:  "docker-version",
+					Usage: "constraint on the docker release to install, e.g \">=20.10 <25\"",
+				},
+				cli.StringFlag{
+					Name:  "docker-user",
+					Usage: "user added to the docker group once provisioned, allowing it to use docker without root",
+				},
+				cli.StringFlag{
+					Name:  "bundle",
+					Usage: "path of a bundle created by `box bundle create` to provision from without internet access",
+				},
+			},
+		},
+		{
+			Name:        "bundle",
+			Description: "Manages bundles for provisioning hosts without internet access",
+			Subcommands: bundleCommands,
+		},
+		{
+			Name:        "firewall",
+			Action:      firewallFn,
+			Description: "Allows traffic to the box and docker ports through the host's firewall, printing the changes made",
+			Flags:       firewallFlags,
+		},
+		{
+			Name:        "register",
+			Action:      registerFn,
+			ArgsUsage:   "ADDRESS",
+			Description: "Adds a remote host into the local host inventory for use by other commands",
+			Flags:       registerFlags,
+		},
+		{
+			Name:        "hosts",
+			Description: "Manages the hosts registered within the local host inventory",
+			Subcommands: hostsCommands,
+		},
+		{
+			Name:        "func",
+			Action:      funcFn,
+			ArgsUsage:   "NAMESPACE BINARY",
+			Description: "Creates a docker image which wraps the binary with a server exposing it's exec and stat endpoints",
+			Flags:       funcFlags,
+		},
+		{
+			Name:        "service",
+			Description: "Manages stateless binary services which serve pushed binaries over http",
+			Subcommands: serviceCommands,
 		},
 	}
 
@@ -98,16 +155,79 @@ func main() {
 }
 
 func initFn(c *cli.Context) {
-	exec, err := box.CreateWithJSON(strings.ToLower(runtime.GOOS), map[string]interface{}{})
-	if err != nil {
-		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to find provisioner for %q", runtime.GOOS))
-		return
+	ctx := context.Background()
+	osName := strings.ToLower(runtime.GOOS)
+
+	if name := c.String("host"); name != "" {
+		host, err := findHost(name)
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to find host %q", name))
+			return
+		}
+
+		executor, err := hostExecutor(host)
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to connect to host %q", name))
+			return
+		}
+
+		defer executor.Close()
+		exec.SetDefaultExecutor(executor)
+
+		var outs bytes.Buffer
+		if err := exec.New(exec.Command("uname -s"), exec.Sync(), exec.Output(&outs)).Exec(ctx); err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to detect operating system of host %q", name))
+			return
+		}
+
+		osName = strings.ToLower(strings.TrimSpace(outs.String()))
 	}
 
-	if err := exec.Exec(context.Background()); err != nil {
-		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to run provisioner for %q", runtime.GOOS))
+	var provisioner ops.Op
+
+	if bundlePath := c.String("bundle"); bundlePath != "" {
+		offline, err := bundle.Open(bundlePath)
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to open bundle %q", bundlePath))
+			return
+		}
+
+		provisioner = bundle.Provisioner{Bundle: offline}
+	} else {
+		created, err := box.CreateWithJSON(osName, map[string]interface{}{
+			"docker_version": c.String("docker-version"),
+		})
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to find provisioner for %q", osName))
+			return
+		}
+
+		provisioner = created
+	}
+
+	if err := provisioner.Exec(ctx); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok {
+			events.Emit(metrics.With(logKey, errLog).With("error", cmdErr.Err).WithMessage("Failed to run provisioner for %q:\n%s", osName, cmdErr.Report()))
+			return
+		}
+
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to run provisioner for %q", osName))
 		return
 	}
+
+	if user := c.String("docker-user"); user != "" {
+		privilege, err := exec.Privilege(ctx)
+		if err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to add %q to docker group", user))
+			return
+		}
+
+		member := users.GroupMember{User: user, Groups: []string{"docker"}, Privilege: privilege}
+		if err := member.Exec(ctx); err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to add %q to docker group", user))
+			return
+		}
+	}
 }
 
 // versionFn defines the action called when seeking the Version detail.
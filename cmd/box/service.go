@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/hosts"
+	"github.com/influx6/box/service"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+)
+
+var serviceCommands = []cli.Command{
+	{
+		Name:      "new",
+		Usage:     "Creates and launches a service for binary functions",
+		ArgsUsage: "NAME",
+		Action:    serviceNewFn,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "addr, a",
+				Value: "127.0.0.1:3000",
+				Usage: "address the service listens on",
+			},
+			cli.StringFlag{
+				Name:  "dir, d",
+				Usage: "directory binaries are stored in, defaults to $BOX_HOME/services/NAME",
+			},
+			cli.StringFlag{
+				Name:   "token",
+				EnvVar: "BOX_SERVICE_TOKEN",
+				Usage:  "token requests must carry, a random one is generated if not provided",
+			},
+		},
+	},
+	{
+		Name:      "add",
+		Usage:     "Pushes a binary to a registered service",
+		ArgsUsage: "BINARY",
+		Action:    serviceAddFn,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "name of the service to push the binary to",
+			},
+			cli.StringFlag{
+				Name:  "as",
+				Usage: "name to register the binary with, defaults to the binary's file name",
+			},
+			cli.StringFlag{
+				Name:   "token",
+				EnvVar: "BOX_SERVICE_TOKEN",
+				Usage:  "token of the service, defaults to the one stored when it was created",
+			},
+		},
+	},
+}
+
+// serviceNewFn defines the action called to register and launch a binary service.
+func serviceNewFn(c *cli.Context) {
+	name := c.Args().First()
+	if name == "" {
+		events.Emit(metrics.With(logKey, errLog).With("error", hosts.ErrInvalidHostName).WithMessage("Usage: box service new NAME"))
+		return
+	}
+
+	dir := c.String("dir")
+	if dir == "" {
+		dir = filepath.Join(hosts.Home(), "services", name)
+	}
+
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to load host inventory"))
+		return
+	}
+
+	// The token is kept within the inventory, reusing the one of an existing service so
+	// clients holding it keep working.
+	token := c.String("token")
+	if existing, err := inv.Get(name); token == "" && err == nil {
+		token = existing.Token
+	}
+
+	if token == "" {
+		if token, err = service.NewToken(); err != nil {
+			events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to generate token for service %q", name))
+			return
+		}
+	}
+
+	if err := inv.Add(hosts.Host{Name: name, Addr: c.String("addr"), Transport: hosts.ServiceTransport, Token: token}, true); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to register service %q", name))
+		return
+	}
+
+	if err := inv.Save(); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to save host inventory %q", inv.Path()))
+		return
+	}
+
+	svc, err := service.New(name, dir)
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to create service %q", name))
+		return
+	}
+
+	svc.Token = token
+
+	fmt.Println("Create service for binary functions")
+	fmt.Printf("Service token stored in %q\n", inv.Path())
+	fmt.Println(color.GreenString(fmt.Sprintf("Service launch on http://%s/", c.String("addr"))))
+
+	if err := svc.ListenAndServe(c.String("addr")); err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Service %q stopped", name))
+	}
+}
+
+// serviceAddFn defines the action called to push a binary to a registered service.
+func serviceAddFn(c *cli.Context) {
+	binary := c.Args().First()
+
+	host, err := findHost(c.String("name"))
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to find service %q", c.String("name")))
+		return
+	}
+
+	if host.Transport != hosts.ServiceTransport {
+		events.Emit(metrics.With(logKey, errLog).With("error", hosts.ErrUnknownTransport).WithMessage("Host %q is not a binary service", host.Name))
+		return
+	}
+
+	name := c.String("as")
+	if name == "" {
+		name = filepath.Base(binary)
+	}
+
+	file, err := os.Open(binary)
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to open binary %q", binary))
+		return
+	}
+
+	defer file.Close()
+
+	fmt.Printf("Push binary %q to service %q(%s)\n", name, host.Name, host.Addr)
+
+	client := service.NewClient(host.Addr)
+	client.Token = host.Token
+
+	if token := c.String("token"); token != "" {
+		client.Token = token
+	}
+
+	stat, err := client.Push(name, file)
+	if err != nil {
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to push binary %q to %q", name, host.Name))
+		return
+	}
+
+	fmt.Printf("Registering binary has %q with route %q\n", stat.Name, stat.Route)
+	fmt.Println(color.GreenString(fmt.Sprintf("Ready for requests at `%s%s`.", host.Name, stat.Route)))
+}
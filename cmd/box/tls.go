@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/influx6/box/certs"
+	"github.com/influx6/box/hosts"
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/dockerd"
+	"github.com/influx6/faux/metrics"
+	"github.com/minio/cli"
+	"github.com/moby/moby/client"
+)
+
+// tlsPort defines the port the docker API of hosts secured by box listens on.
+const tlsPort = "2376"
+
+var tlsFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "san",
+		Value: &cli.StringSlice{},
+		Usage: "additional IP address or DNS name of the host for it's server certificate, may be repeated",
+	},
+}
+
+// hostsTLSFn defines the action called to secure the docker API of a registered host with
+// mutual TLS.
+func hostsTLSFn(c *cli.Context) {
+	name := c.Args().First()
+
+	if err := secureHost(name, c.StringSlice("san")); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok {
+			events.Emit(metrics.With(logKey, errLog).With("error", cmdErr.Err).WithMessage("Failed to secure docker of host %q:\n%s", name, cmdErr.Report()))
+			return
+		}
+
+		events.Emit(metrics.With(logKey, errLog).With("error", err).WithMessage("Failed to secure docker of host %q", name))
+		return
+	}
+
+	fmt.Println(color.GreenString(fmt.Sprintf("Docker of host %q now requires TLS client certificates on port %s", name, tlsPort)))
+}
+
+func secureHost(name string, sans []string) error {
+	inv, err := hosts.Load(hosts.DefaultPath())
+	if err != nil {
+		return err
+	}
+
+	host, err := inv.Get(name)
+	if err != nil {
+		return err
+	}
+
+	addr, _, err := net.SplitHostPort(host.Addr)
+	if err != nil {
+		return err
+	}
+
+	var ips []net.IP
+	var dnsNames []string
+	for _, san := range append([]string{addr}, sans...) {
+		if ip := net.ParseIP(san); ip != nil {
+			ips = append(ips, ip)
+			continue
+		}
+
+		dnsNames = append(dnsNames, san)
+	}
+
+	ca, err := certs.LoadOrCreateCA(hosts.CADir(), "box CA")
+	if err != nil {
+		return err
+	}
+
+	server, err := ca.IssueServer(host.Name, ips, dnsNames)
+	if err != nil {
+		return err
+	}
+
+	serverKey, err := server.KeyPEM()
+	if err != nil {
+		return err
+	}
+
+	executor, err := hostExecutor(host)
+	if err != nil {
+		return err
+	}
+
+	defer executor.Close()
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	hostFacts, err := facts.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	privilege, err := exec.Privilege(ctx)
+	if err != nil {
+		return err
+	}
+
+	configure := dockerd.TLSConfigure{
+		CACert:     ca.CertPEM(),
+		Cert:       server.CertPEM(),
+		Key:        serverKey,
+		InitSystem: hostFacts.InitSystem,
+		Privilege:  privilege,
+	}
+
+	if err := configure.Exec(ctx); err != nil {
+		return err
+	}
+
+	clientPair, err := ca.IssueClient("box")
+	if err != nil {
+		return err
+	}
+
+	dir := hosts.CertsDir(host.Name)
+	if err := clientPair.Save(dir, certs.CertFile, certs.KeyFile); err != nil {
+		return err
+	}
+
+	// Only the CA certificate is kept alongside the client certificate, it's key stays
+	// within the CA's directory.
+	if err := ioutil.WriteFile(filepath.Join(dir, certs.CAFile), ca.CertPEM(), 0644); err != nil {
+		return err
+	}
+
+	host.DockerAddr = "tcp://" + net.JoinHostPort(addr, tlsPort)
+	host.DockerCerts = dir
+
+	if err := inv.Update(host); err != nil {
+		return err
+	}
+
+	return inv.Save()
+}
+
+// dockerClient returns a docker client for the named host using it's client certificate,
+// or a client configured from the environment if name is empty.
+func dockerClient(name string) (*client.Client, error) {
+	if name == "" {
+		return client.NewEnvClient()
+	}
+
+	host, err := findHost(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if host.DockerAddr == "" {
+		return nil, fmt.Errorf("host %q has no TLS secured docker API, run `box hosts tls %s` first", name, name)
+	}
+
+	config, err := certs.ClientConfig(host.DockerCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: config},
+	}
+
+	return client.NewClient(host.DockerAddr, "", httpClient, nil)
+}
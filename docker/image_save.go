@@ -14,6 +14,7 @@ func (d *DockerCaster) ImageSave(ops []string) (*ImageSaveOp, error) {
 	var spell ImageSaveOp
 
 	spell.ops = ops
+	spell.client = d.client
 
 	return &spell, nil
 }
@@ -34,7 +35,7 @@ type ImageSaveOp struct {
 
 // Op returns a object implementing the ops.Op interface.
 func (cm *ImageSaveOp) Op(callback ImageSaveResponseCallback) ops.Op {
-	return &onceImageSaveOp{spell: cm, callback: cb}
+	return &onceImageSaveOp{spell: cm, callback: callback}
 }
 
 type onceImageSaveOp struct {
@@ -49,8 +50,27 @@ func (cm *onceImageSaveOp) Exec(ctx context.CancelContext) error {
 
 // Exec executes the image creation for the underline docker server pointed to.
 func (cm *ImageSaveOp) Exec(ctx context.CancelContext, callback ImageSaveResponseCallback) error {
+	if cm.client == nil {
+		return ErrNoDockerClientProvided
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Cancel context if are done or if context has expired.
+	reqCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+			return
+		case <-done:
+			return
+		}
+	}()
+
 	// Execute client ImageSave method.
-	ret0, err := cm.client.ImageSave(cm.ops)
+	ret0, err := cm.client.ImageSave(reqCtx, cm.ops)
 	if err != nil {
 		return err
 	}
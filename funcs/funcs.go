@@ -0,0 +1,259 @@
+// Package funcs implements the creation of docker images which wrap a binary with a
+// small server exposing the binary over http through `{binary}/exec` and `{binary}/stat`
+// routes.
+package funcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker/api/types"
+	"github.com/influx6/box/docker"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/moz/gen/filesystem"
+)
+
+// errors
+var (
+	ErrNoBinaryProvided = errors.New("No binary path was provided")
+	ErrNoImageIDFound   = errors.New("Image build completed without an image id")
+	ErrInvalidImageName = errors.New("Image name must contain letters or digits")
+	ErrInvalidNamespace = errors.New("Image namespace must be lowercase path components, optionally prefixed by a registry")
+)
+
+// trailers set by the wrapper server on `{binary}/exec` responses.
+const (
+	ExitCodeTrailer = "X-Box-Exit-Code"
+	StderrTrailer   = "X-Box-Stderr"
+)
+
+// defaults
+const (
+	DefaultBaseImage    = "alpine:latest"
+	DefaultBuilderImage = "golang:1.9-alpine"
+	DefaultPort         = 8080
+)
+
+var (
+	builtImageID = regexp.MustCompile(`Successfully built ([0-9a-f]+)`)
+
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+	repeatedSeps     = regexp.MustCompile(`[._-]{2,}`)
+	validNamespace   = regexp.MustCompile(`^([a-z0-9.-]+(:[0-9]+)?/)?[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+
+	dockerfileTemplate = template.Must(template.New("Dockerfile").Parse(`FROM {{.BuilderImage}} AS builder
+COPY server.go /go/src/boxfunc/server.go
+RUN CGO_ENABLED=0 go build -o /boxfunc /go/src/boxfunc/server.go
+
+FROM {{.BaseImage}}
+COPY --from=builder /boxfunc /usr/local/bin/boxfunc
+COPY {{.Name}} /usr/local/bin/{{.Name}}
+RUN chmod +x /usr/local/bin/{{.Name}} /usr/local/bin/boxfunc
+ENV BOX_FUNC_NAME={{.Name}} BOX_FUNC_BIN=/usr/local/bin/{{.Name}} BOX_FUNC_ADDR=:{{.Port}}
+EXPOSE {{.Port}}
+ENTRYPOINT ["/usr/local/bin/boxfunc"]
+`))
+)
+
+// Image defines the details used to wrap a binary into a docker image.
+type Image struct {
+	// Binary sets the path of the binary to be wrapped.
+	Binary string
+
+	// Name sets the name used for the image tag and routes, it defaults to the binary's file
+	// name. It's normalized with NormalizeName as docker only accepts lowercase names.
+	Name string
+
+	// Namespace if set adds a supplementary `{Namespace}/{Name}` tag to the image.
+	Namespace string
+
+	// BaseImage sets the image the wrapped binary runs in, it defaults to DefaultBaseImage.
+	BaseImage string
+
+	// BuilderImage sets the image used to compile the wrapper server, it defaults to DefaultBuilderImage.
+	BuilderImage string
+
+	// Port sets the port the wrapper server listens on, it defaults to DefaultPort.
+	Port int
+}
+
+// NormalizeName returns the name lowercased, with characters docker rejects within image
+// names replaced by `-`, e.g `Geth Bin` becomes `geth-bin`.
+func NormalizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = repeatedSeps.ReplaceAllString(name, "-")
+	return strings.Trim(name, "._-")
+}
+
+// Validate returns an error if the image can not be tagged with it's name and namespace.
+func (img Image) Validate() error {
+	if img.Binary == "" {
+		return ErrNoBinaryProvided
+	}
+
+	if name := img.withDefaults().Name; name == "" {
+		return fmt.Errorf("%s: %q", ErrInvalidImageName, img.Name)
+	}
+
+	if img.Namespace != "" && !validNamespace.MatchString(img.Namespace) {
+		return fmt.Errorf("%s: %q", ErrInvalidNamespace, img.Namespace)
+	}
+
+	return nil
+}
+
+// withDefaults returns a copy of the Image with all empty fields set to their defaults.
+func (img Image) withDefaults() Image {
+	if img.Name == "" {
+		img.Name = filepath.Base(img.Binary)
+	}
+
+	img.Name = NormalizeName(img.Name)
+
+	if img.BaseImage == "" {
+		img.BaseImage = DefaultBaseImage
+	}
+
+	if img.BuilderImage == "" {
+		img.BuilderImage = DefaultBuilderImage
+	}
+
+	if img.Port == 0 {
+		img.Port = DefaultPort
+	}
+
+	return img
+}
+
+// Tags returns the list of tags the image will be built with.
+func (img Image) Tags() []string {
+	img = img.withDefaults()
+
+	tags := []string{img.Name}
+	if img.Namespace != "" {
+		tags = append(tags, fmt.Sprintf("%s/%s", img.Namespace, img.Name))
+	}
+
+	return tags
+}
+
+// Dockerfile returns the generated Dockerfile for the image.
+func (img Image) Dockerfile() (string, error) {
+	var buf bytes.Buffer
+	if err := dockerfileTemplate.Execute(&buf, img.withDefaults()); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Filesystem returns the in-memory build context containing the Dockerfile, the wrapper
+// server source and the binary to be wrapped.
+func (img Image) Filesystem() (filesystem.Filesystem, error) {
+	if err := img.Validate(); err != nil {
+		return nil, err
+	}
+
+	img = img.withDefaults()
+
+	binary, err := ioutil.ReadFile(img.Binary)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerfile, err := img.Dockerfile()
+	if err != nil {
+		return nil, err
+	}
+
+	return filesystem.FileSystem(
+		filesystem.File("Dockerfile", filesystem.Content(dockerfile)),
+		filesystem.File("server.go", filesystem.Content(serverSource)),
+		filesystem.File(img.Name, filesystem.Content(string(binary))),
+	), nil
+}
+
+// Build builds the image through the provided DockerCaster, returning the id of the created image.
+func (img Image) Build(ctx context.CancelContext, caster *docker.DockerCaster, progress io.Writer) (string, error) {
+	fs, err := img.Filesystem()
+	if err != nil {
+		return "", err
+	}
+
+	tags := img.Tags()
+
+	builder, err := caster.BuildImage(tags[0], filesystem.GzipTarFS(fs), docker.ImageSupplementaryTags(tags[1:]...))
+	if err != nil {
+		return "", err
+	}
+
+	var imageID string
+
+	err = builder.Exec(ctx, func(res types.ImageBuildResponse) error {
+		defer res.Body.Close()
+
+		id, err := readImageID(res.Body, progress)
+		imageID = id
+		return err
+	})
+
+	return imageID, err
+}
+
+// buildMessage defines the json messages streamed back by the docker daemon during an image build.
+type buildMessage struct {
+	Stream string `json:"stream"`
+	Error  string `json:"error"`
+	Aux    struct {
+		ID string `json:"ID"`
+	} `json:"aux"`
+}
+
+// readImageID reads the build response stream, writing all build output into progress if not nil,
+// returning the id of the built image.
+func readImageID(body io.Reader, progress io.Writer) (string, error) {
+	var imageID string
+
+	decoder := json.NewDecoder(body)
+	for {
+		var msg buildMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return "", err
+		}
+
+		if msg.Error != "" {
+			return "", errors.New(msg.Error)
+		}
+
+		if progress != nil && msg.Stream != "" {
+			io.WriteString(progress, msg.Stream)
+		}
+
+		if msg.Aux.ID != "" {
+			imageID = msg.Aux.ID
+			continue
+		}
+
+		if match := builtImageID.FindStringSubmatch(msg.Stream); imageID == "" && len(match) == 2 {
+			imageID = match[1]
+		}
+	}
+
+	if imageID == "" {
+		return "", ErrNoImageIDFound
+	}
+
+	return imageID, nil
+}
@@ -0,0 +1,59 @@
+package funcs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/box/funcs"
+	"github.com/influx6/faux/tests"
+)
+
+func TestImageDockerfile(t *testing.T) {
+	img := funcs.Image{Namespace: "io", Binary: "./cmd/geth/geth-bin"}
+
+	tags := img.Tags()
+	if len(tags) != 2 || tags[0] != "geth-bin" || tags[1] != "io/geth-bin" {
+		tests.Failed("Should have generated image tags from binary name: %+q", tags)
+	}
+	tests.Passed("Should have generated image tags from binary name")
+
+	dockerfile, err := img.Dockerfile()
+	if err != nil {
+		tests.Failed("Should have generated Dockerfile: %+q", err)
+	}
+	tests.Passed("Should have generated Dockerfile")
+
+	for _, line := range []string{
+		"FROM " + funcs.DefaultBaseImage,
+		"COPY geth-bin /usr/local/bin/geth-bin",
+		"BOX_FUNC_NAME=geth-bin",
+		"EXPOSE 8080",
+		`ENTRYPOINT ["/usr/local/bin/boxfunc"]`,
+	} {
+		if !strings.Contains(dockerfile, line) {
+			tests.Info("Dockerfile: %s", dockerfile)
+			tests.Failed("Should have found %q in Dockerfile", line)
+		}
+	}
+	tests.Passed("Should have found expected instructions in Dockerfile")
+}
+
+func TestImageName(t *testing.T) {
+	img := funcs.Image{Namespace: "registry.io:5000/team", Binary: "./bin/Geth Bin_"}
+
+	tags := img.Tags()
+	if len(tags) != 2 || tags[0] != "geth-bin" || tags[1] != "registry.io:5000/team/geth-bin" {
+		tests.Failed("Should have normalized image tags: %+q", tags)
+	}
+	tests.Passed("Should have normalized image tags")
+
+	if err := (funcs.Image{Binary: "./bin/---"}).Validate(); err == nil {
+		tests.Failed("Should have failed to validate image without usable name")
+	}
+	tests.Passed("Should have failed to validate image without usable name")
+
+	if err := (funcs.Image{Namespace: "IO", Binary: "./geth"}).Validate(); err == nil {
+		tests.Failed("Should have failed to validate image with uppercase namespace")
+	}
+	tests.Passed("Should have failed to validate image with uppercase namespace")
+}
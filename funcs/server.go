@@ -0,0 +1,115 @@
+package funcs
+
+// serverSource contains the source of the wrapper server compiled within the image build.
+// The server exposes the wrapped binary through the following routes:
+//
+//	/{BOX_FUNC_NAME}/exec: runs the binary with the request body as stdin and streams back stdout,
+//	setting the exit code and base64 encoded tail of stderr as the X-Box-Exit-Code and
+//	X-Box-Stderr trailers.
+//	/{BOX_FUNC_NAME}/stat: returns the stats of requests executed by the server.
+const serverSource = `package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// stderrTail defines the number of bytes of stderr kept for the X-Box-Stderr trailer.
+const stderrTail = 8 * 1024
+
+type stats struct {
+	sync.Mutex
+	Requests      int64     ` + "`json:\"requests\"`" + `
+	Failures      int64     ` + "`json:\"failures\"`" + `
+	TotalDuration string    ` + "`json:\"total_duration\"`" + `
+	LastRun       time.Time ` + "`json:\"last_run\"`" + `
+	total         time.Duration
+}
+
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+	if flusher, ok := fw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+func main() {
+	name := os.Getenv("BOX_FUNC_NAME")
+	bin := os.Getenv("BOX_FUNC_BIN")
+	addr := os.Getenv("BOX_FUNC_ADDR")
+
+	var st stats
+
+	http.HandleFunc("/"+name+"/exec", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST requests are allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var errs bytes.Buffer
+		cmd := exec.Command(bin, r.URL.Query()["arg"]...)
+		cmd.Stdin = r.Body
+		cmd.Stdout = flushWriter{w: w}
+		cmd.Stderr = &errs
+
+		w.Header().Set("Trailer", "X-Box-Exit-Code, X-Box-Stderr")
+		w.Header().Set("Content-Type", "application/octet-stream")
+
+		start := time.Now()
+		err := cmd.Run()
+		elapsed := time.Since(start)
+
+		exitCode := 0
+		if err != nil {
+			exitCode = -1
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				if status, ok := exitErr.Sys().(interface{ ExitStatus() int }); ok {
+					exitCode = status.ExitStatus()
+				}
+			}
+		}
+
+		stderr := errs.Bytes()
+		if len(stderr) > stderrTail {
+			stderr = stderr[len(stderr)-stderrTail:]
+		}
+
+		w.Header().Set("X-Box-Exit-Code", strconv.Itoa(exitCode))
+		w.Header().Set("X-Box-Stderr", base64.StdEncoding.EncodeToString(stderr))
+
+		st.Lock()
+		st.Requests++
+		if err != nil {
+			st.Failures++
+		}
+		st.total += elapsed
+		st.TotalDuration = st.total.String()
+		st.LastRun = start
+		st.Unlock()
+	})
+
+	http.HandleFunc("/"+name+"/stat", func(w http.ResponseWriter, r *http.Request) {
+		st.Lock()
+		defer st.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&st)
+	})
+
+	log.Printf("Serving %q on %s", name, addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+`
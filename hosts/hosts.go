@@ -0,0 +1,254 @@
+// Package hosts implements a persistent inventory of remote hosts registered with box,
+// allowing commands to target a named host instead of the local machine.
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// errors
+var (
+	ErrHostNotFound      = errors.New("Host not found in inventory")
+	ErrHostExists        = errors.New("Host already exists in inventory")
+	ErrInvalidHostName   = errors.New("Host name must be provided")
+	ErrUnsafeHostName    = errors.New("Host name must not contain path separators or ..")
+	ErrInvalidHostAddr   = errors.New("Host address must be in host:port format")
+	ErrUnknownTransport  = errors.New("Host transport is not supported")
+	ErrInvalidHostLabels = errors.New("Host labels must be in key=value format")
+)
+
+// Transports supported by box for reaching a registered host.
+const (
+	SSHTransport     = "ssh"
+	LocalTransport   = "local"
+	ServiceTransport = "service"
+)
+
+// HomeEnv defines the environment variable which can be used to override the box home directory.
+const HomeEnv = "BOX_HOME"
+
+// Host defines the details box holds about a registered host.
+type Host struct {
+	Name         string            `toml:"name"`
+	Addr         string            `toml:"addr"`
+	Transport    string            `toml:"transport"`
+	User         string            `toml:"user"`
+	Identity     string            `toml:"identity"`
+	Fingerprints []string          `toml:"fingerprints"`
+	Labels       map[string]string `toml:"labels"`
+	Added        time.Time         `toml:"added"`
+
+	// DockerAddr contains the address of the host's TLS secured docker API, like
+	// `tcp://10.0.0.2:2376`, and DockerCerts the directory of the client certificate
+	// used to connect to it.
+	DockerAddr  string `toml:"docker_addr"`
+	DockerCerts string `toml:"docker_certs"`
+
+	// Token contains the token authenticating with the host's binary service.
+	Token string `toml:"token"`
+}
+
+// Validate returns an error if the host does not contain the necessary details to be
+// stored in the inventory.
+func (h Host) Validate() error {
+	if strings.TrimSpace(h.Name) == "" {
+		return ErrInvalidHostName
+	}
+
+	// The name is used within paths like CertsDir, so it must not escape them.
+	if strings.ContainsAny(h.Name, `/\`) || strings.Contains(h.Name, "..") {
+		return ErrUnsafeHostName
+	}
+
+	switch h.Transport {
+	case SSHTransport, ServiceTransport:
+		if _, _, err := net.SplitHostPort(h.Addr); err != nil {
+			return ErrInvalidHostAddr
+		}
+	case LocalTransport:
+	default:
+		return ErrUnknownTransport
+	}
+
+	return nil
+}
+
+// ParseLabels returns a map of labels from a list of key=value pairs.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		index := strings.Index(pair, "=")
+		if index <= 0 {
+			return nil, ErrInvalidHostLabels
+		}
+
+		labels[pair[:index]] = pair[index+1:]
+	}
+
+	return labels, nil
+}
+
+//===============================================================================================================
+
+// Home returns the directory used by box for its local state, which defaults
+// to ~/.box unless overridden by the BOX_HOME environment variable.
+func Home() string {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return home
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".box")
+}
+
+// CADir returns the directory of the certificate authority signing the docker
+// certificates of hosts.
+func CADir() string {
+	return filepath.Join(Home(), "ca")
+}
+
+// CertsDir returns the directory of the docker client certificate of the named host.
+func CertsDir(name string) string {
+	return filepath.Join(Home(), "certs", name)
+}
+
+// DefaultPath returns the path of the default inventory file.
+func DefaultPath() string {
+	return filepath.Join(Home(), "hosts.toml")
+}
+
+// Inventory holds the list of hosts registered with box and persists them
+// into a toml file.
+type Inventory struct {
+	path  string
+	hosts map[string]Host
+}
+
+// inventoryFile defines the layout of the inventory toml file.
+type inventoryFile struct {
+	Hosts []Host `toml:"hosts"`
+}
+
+// Load returns a new Inventory from the provided file path, if the file does not
+// exists then an empty inventory is returned.
+func Load(path string) (*Inventory, error) {
+	inv := &Inventory{path: path, hosts: map[string]Host{}}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return inv, nil
+		}
+
+		return nil, err
+	}
+
+	var file inventoryFile
+	if _, err := toml.Decode(string(data), &file); err != nil {
+		return nil, fmt.Errorf("Failed to decode inventory %q: %+q", path, err)
+	}
+
+	for _, host := range file.Hosts {
+		inv.hosts[host.Name] = host
+	}
+
+	return inv, nil
+}
+
+// Path returns the file path of the inventory.
+func (inv *Inventory) Path() string {
+	return inv.path
+}
+
+// Add adds the host into the inventory. If replace is false and a host with the same
+// name exists, then ErrHostExists is returned.
+func (inv *Inventory) Add(host Host, replace bool) error {
+	if err := host.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := inv.hosts[host.Name]; ok && !replace {
+		return ErrHostExists
+	}
+
+	if host.Added.IsZero() {
+		host.Added = time.Now().UTC()
+	}
+
+	inv.hosts[host.Name] = host
+	return nil
+}
+
+// Update replaces the details of an existing host in the inventory.
+func (inv *Inventory) Update(host Host) error {
+	if _, ok := inv.hosts[host.Name]; !ok {
+		return ErrHostNotFound
+	}
+
+	return inv.Add(host, true)
+}
+
+// Remove removes the host with the giving name from the inventory.
+func (inv *Inventory) Remove(name string) error {
+	if _, ok := inv.hosts[name]; !ok {
+		return ErrHostNotFound
+	}
+
+	delete(inv.hosts, name)
+	return nil
+}
+
+// Get returns the host with the giving name.
+func (inv *Inventory) Get(name string) (Host, error) {
+	host, ok := inv.hosts[name]
+	if !ok {
+		return Host{}, ErrHostNotFound
+	}
+
+	return host, nil
+}
+
+// List returns all hosts in the inventory sorted by name.
+func (inv *Inventory) List() []Host {
+	list := make([]Host, 0, len(inv.hosts))
+	for _, host := range inv.hosts {
+		list = append(list, host)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// Save writes the inventory into it's file, creating the parent directory if needed.
+// The file is first written into a temporary file which then replaces the existing one.
+func (inv *Inventory) Save() error {
+	if err := os.MkdirAll(filepath.Dir(inv.path), 0700); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(inventoryFile{Hosts: inv.List()}); err != nil {
+		return err
+	}
+
+	tmp := inv.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, inv.path)
+}
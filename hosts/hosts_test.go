@@ -0,0 +1,87 @@
+package hosts_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influx6/box/hosts"
+	"github.com/influx6/faux/tests"
+)
+
+func TestInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-hosts")
+	if err != nil {
+		tests.Failed("Should have created temporary directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hosts.toml")
+
+	inv, err := hosts.Load(path)
+	if err != nil {
+		tests.Failed("Should have loaded empty inventory: %+q", err)
+	}
+	tests.Passed("Should have loaded empty inventory")
+
+	thunder := hosts.Host{
+		Name:         "thunder.io",
+		Addr:         "192.40.30.90:5060",
+		Transport:    hosts.SSHTransport,
+		Fingerprints: []string{"SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"},
+		Labels:       map[string]string{"env": "prod"},
+	}
+
+	if err := inv.Add(thunder, false); err != nil {
+		tests.Failed("Should have added host into inventory: %+q", err)
+	}
+	tests.Passed("Should have added host into inventory")
+
+	if err := inv.Add(thunder, false); err != hosts.ErrHostExists {
+		tests.Failed("Should have failed to add existing host: %+q", err)
+	}
+	tests.Passed("Should have failed to add existing host")
+
+	if err := inv.Add(hosts.Host{Name: "bad", Addr: "192.40.30.90", Transport: hosts.SSHTransport}, false); err != hosts.ErrInvalidHostAddr {
+		tests.Failed("Should have failed to add host with invalid address: %+q", err)
+	}
+	tests.Passed("Should have failed to add host with invalid address")
+
+	if err := inv.Add(hosts.Host{Name: "../ca", Transport: hosts.LocalTransport}, false); err != hosts.ErrUnsafeHostName {
+		tests.Failed("Should have failed to add host with name escaping paths: %+q", err)
+	}
+	tests.Passed("Should have failed to add host with name escaping paths")
+
+	if err := inv.Save(); err != nil {
+		tests.Failed("Should have saved inventory: %+q", err)
+	}
+	tests.Passed("Should have saved inventory")
+
+	loaded, err := hosts.Load(path)
+	if err != nil {
+		tests.Failed("Should have loaded saved inventory: %+q", err)
+	}
+	tests.Passed("Should have loaded saved inventory")
+
+	host, err := loaded.Get("thunder.io")
+	if err != nil {
+		tests.Failed("Should have found registered host: %+q", err)
+	}
+	tests.Passed("Should have found registered host")
+
+	if host.Addr != thunder.Addr || host.Labels["env"] != "prod" || len(host.Fingerprints) != 1 {
+		tests.Failed("Should have matching host details: %#v", host)
+	}
+	tests.Passed("Should have matching host details")
+
+	if err := loaded.Remove("thunder.io"); err != nil {
+		tests.Failed("Should have removed host: %+q", err)
+	}
+	tests.Passed("Should have removed host")
+
+	if len(loaded.List()) != 0 {
+		tests.Failed("Should have no hosts in inventory")
+	}
+	tests.Passed("Should have no hosts in inventory")
+}
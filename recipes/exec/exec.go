@@ -4,8 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/influx6/faux/context"
 	"github.com/influx6/faux/metrics"
@@ -16,9 +16,14 @@ const (
 )
 
 var (
-	ErrCommandFailed = errors.New("Command failed to execute succcesfully")
+	ErrCommandFailed    = errors.New("Command failed to execute succcesfully")
+	ErrCommandCancelled = errors.New("Command was terminated due to context cancellation")
 )
 
+// DefaultGracePeriod defines the default duration a command is given to exit after
+// receiving SIGTERM due to context cancellation before it is killed with SIGKILL.
+const DefaultGracePeriod = 10 * time.Second
+
 // CommanderOption defines a function type that aguments a commander's field.
 type CommanderOption func(*Commander)
 
@@ -89,6 +94,54 @@ func Envs(envs map[string]string) CommanderOption {
 	}
 }
 
+// Using sets the Executor through which the Commander runs it's command.
+func Using(executor Executor) CommanderOption {
+	return func(cm *Commander) {
+		cm.Executor = executor
+	}
+}
+
+// GracePeriod sets the duration a cancelled command is given to exit before it is killed.
+func GracePeriod(d time.Duration) CommanderOption {
+	return func(cm *Commander) {
+		cm.GracePeriod = d
+	}
+}
+
+// TailSize sets the number of bytes of stdout and stderr kept for the Commander's Result.
+func TailSize(size int) CommanderOption {
+	return func(cm *Commander) {
+		cm.TailSize = size
+	}
+}
+
+// Redact sets the Redactor used to mask secrets for the Commander.
+func Redact(redactor *Redactor) CommanderOption {
+	return func(cm *Commander) {
+		cm.Redactor = redactor
+	}
+}
+
+// Secrets adds the provided values to the list of secrets masked for the Commander.
+func Secrets(values ...string) CommanderOption {
+	return func(cm *Commander) {
+		cm.Secrets = append(cm.Secrets, values...)
+	}
+}
+
+// CleanEnv sets the Commander to run it's command without inheriting the parent's environment,
+// except for the allowed variables, which default to DefaultAllowedEnvs if none are provided.
+func CleanEnv(allowed ...string) CommanderOption {
+	return func(cm *Commander) {
+		if len(allowed) == 0 {
+			allowed = DefaultAllowedEnvs
+		}
+
+		cm.CleanEnv = true
+		cm.AllowedEnvs = allowed
+	}
+}
+
 // Apply takes the giving series of CommandOption returning a function that always applies them to passed in commanders.
 func Apply(ops ...CommanderOption) CommanderOption {
 	return func(cm *Commander) {
@@ -103,15 +156,39 @@ func Apply(ops ...CommanderOption) CommanderOption {
 // Commander allows you to set the binary to use and flag, where each defaults to /bin/sh for binary
 // and -c for flag respectively.
 type Commander struct {
-	Async   bool
-	Command string
-	Binary  string
-	Flag    string
-	Envs    map[string]string
-	In      io.Reader
-	Out     io.Writer
-	Err     io.Writer
-	Metrics metrics.Metrics
+	Async    bool
+	Command  string
+	Binary   string
+	Flag     string
+	Envs     map[string]string
+	In       io.Reader
+	Out      io.Writer
+	Err      io.Writer
+	Metrics  metrics.Metrics
+	Executor Executor
+
+	// GracePeriod sets the duration the command is given to exit after receiving SIGTERM
+	// before it is killed, it defaults to DefaultGracePeriod.
+	GracePeriod time.Duration
+
+	// TailSize sets the number of bytes of stdout and stderr kept in the Result, it
+	// defaults to DefaultTailSize and a negative value disables it.
+	TailSize int
+
+	// Redactor sets the Redactor used to mask secrets within the environment, command and
+	// output emitted into metrics or kept in the Result, it defaults to DefaultRedactor.
+	Redactor *Redactor
+
+	// Secrets sets explicit secret values to be masked in addition to those found by the Redactor.
+	Secrets []string
+
+	// CleanEnv sets the command to not inherit the parent's environment, except for the
+	// variables listed in AllowedEnvs.
+	CleanEnv    bool
+	AllowedEnvs []string
+
+	// Result contains the details of the last execution of the Commander.
+	Result *Result
 }
 
 // New returns a new Commander instance.
@@ -125,7 +202,22 @@ func New(ops ...CommanderOption) *Commander {
 	return cm
 }
 
-// Exec executes giving command associated within the command with os/exec.
+// Run executes the command asynchronously through the default executor, applying the
+// option first, which recipes use to pass on their DoWithCmd.
+func Run(ctx context.CancelContext, command string, do CommanderOption) error {
+	cmd := New(Command(command), Async())
+
+	if do != nil {
+		do(cmd)
+	}
+
+	return cmd.Exec(ctx)
+}
+
+// Exec executes giving command associated within the command through the Commander's Executor,
+// which defaults to the package's default Executor if not set. The details of the execution are
+// stored in the Commander's Result, and a *CommandError is returned if the command fails.
+// In both sync and async mode, the command is terminated if the context gets cancelled.
 func (c *Commander) Exec(ctx context.CancelContext) error {
 	if c.Metrics == nil {
 		c.Metrics = metrics.New()
@@ -148,52 +240,81 @@ func (c *Commander) Exec(ctx context.CancelContext) error {
 		execCommand = append(execCommand, c.Binary, c.Flag, c.Command)
 	}
 
-	cmder := exec.Command(execCommand[0], execCommand[1:]...)
-	cmder.Stderr = c.Err
-	cmder.Stdin = c.In
-	cmder.Stdout = c.Out
-	cmder.Env = os.Environ()
+	executor := c.Executor
+	if executor == nil {
+		executor = DefaultExecutor()
+	}
+
+	tailSize := c.TailSize
+	if tailSize == 0 {
+		tailSize = DefaultTailSize
+	}
 
-	if c.Envs != nil {
-		for name, val := range c.Envs {
-			cmder.Env = append(cmder.Env, fmt.Sprintf("%s=%s", name, val))
-		}
+	outs, errs := newTailBuffer(tailSize), newTailBuffer(tailSize)
+
+	req := Request{
+		Args:        execCommand,
+		Envs:        c.Envs,
+		CleanEnv:    c.CleanEnv,
+		AllowedEnvs: c.AllowedEnvs,
+		In:          c.In,
+		Out:         teeWriter(c.Out, outs),
+		Err:         teeWriter(c.Err, errs),
+		GracePeriod: c.GracePeriod,
 	}
 
+	redactor := c.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+
+	envs := req.Environ()
+	redactor = redactor.Learn(envs).With(c.Secrets...)
+
 	c.Metrics.Emit(metrics.WithFields(metrics.Fields{
 		"opid":    ExecLogKey,
-		"command": execCommand,
-		"envs":    cmder.Env,
+		"command": redactor.Strings(execCommand),
+		"envs":    redactor.Env(envs),
 	}).WithMessage("Executing native commands"))
 
-	if !c.Async {
-		return cmder.Run()
-	}
+	start := time.Now()
+	err := executor.Execute(ctx, req)
 
-	if err := cmder.Start(); err != nil {
-		return err
+	c.Result = &Result{
+		Command:  redactor.String(strings.Join(execCommand, " ")),
+		Duration: time.Since(start),
+		Stdout:   redactor.Bytes(outs.Bytes()),
+		Stderr:   redactor.Bytes(errs.Bytes()),
 	}
 
-	go func() {
-		<-ctx.Done()
-		if cmder.Process == nil {
-			return
-		}
+	if named, ok := executor.(fmt.Stringer); ok {
+		c.Result.Executor = named.String()
+	}
 
-		cmder.Process.Kill()
-	}()
+	if err == nil {
+		return nil
+	}
 
-	if err := cmder.Wait(); err != nil {
-		return err
+	c.Result.ExitCode = -1
+	if exitErr, ok := err.(*ExitError); ok {
+		c.Result.ExitCode = exitErr.Code
+		c.Result.Signal = exitErr.Signal
 	}
 
-	if cmder.ProcessState == nil {
-		return nil
+	select {
+	case <-ctx.Done():
+		err = ErrCommandCancelled
+	default:
 	}
 
-	if !cmder.ProcessState.Success() {
-		return ErrCommandFailed
+	return &CommandError{Result: *c.Result, Err: err}
+}
+
+// teeWriter returns a writer which writes into both w and tail, or only tail if w is nil.
+func teeWriter(w io.Writer, tail io.Writer) io.Writer {
+	if w == nil {
+		return tail
 	}
 
-	return nil
+	return io.MultiWriter(w, tail)
 }
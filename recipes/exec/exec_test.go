@@ -3,11 +3,15 @@ package exec_test
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
 	"github.com/influx6/faux/tests"
 )
 
@@ -45,11 +49,41 @@ func TestCatCommand(t *testing.T) {
 	tests.Passed("Should have reeived contents from command")
 }
 
+func TestFailedCommand(t *testing.T) {
+	failCmd := exec.New(exec.Command("echo 'moving on' && echo 'no such package' >&2 && exit 100"), exec.Sync(), exec.TailSize(8))
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	err := failCmd.Exec(ctx)
+	if err == nil {
+		tests.Failed("Should have failed to execute command")
+	}
+	tests.Passed("Should have failed to execute command")
+
+	cmdErr, ok := err.(*exec.CommandError)
+	if !ok {
+		tests.Failed("Should have received a CommandError: %#v", err)
+	}
+	tests.Passed("Should have received a CommandError")
+
+	if cmdErr.ExitCode != 100 {
+		tests.Failed("Should have received command exit code: %d", cmdErr.ExitCode)
+	}
+	tests.Passed("Should have received command exit code")
+
+	if string(cmdErr.Stderr) != "package\n" || string(cmdErr.Stdout) != "ving on\n" {
+		tests.Failed("Should have received bounded output tails: %q %q", cmdErr.Stdout, cmdErr.Stderr)
+	}
+	tests.Passed("Should have received bounded output tails")
+	tests.Info("Report: %s", cmdErr.Report())
+}
+
 func TestWgetCommand(t *testing.T) {
-	defer os.Remove("index.html")
+	executor := exectest.New()
+	executor.Expect("wget www.google.com").Stderr("'index.html' saved [11408]\n")
 
 	var outs, errs bytes.Buffer
-	lsCmd := exec.New(exec.Command("wget www.google.com"), exec.Sync(), exec.Output(&outs), exec.Err(&errs), exec.Binary("/bin/bash", "-c"))
+	lsCmd := exec.New(exec.Command("wget www.google.com"), exec.Sync(), exec.Output(&outs), exec.Err(&errs), exec.Binary("/bin/bash", "-c"), exec.Using(executor))
 	ctx, cn := context.WithTimeout(context.Background(), 50*time.Second)
 	defer cn()
 
@@ -59,5 +93,148 @@ func TestWgetCommand(t *testing.T) {
 		tests.Failed("Should have succcesfully executed command: %+q", err)
 	}
 	tests.Passed("Should have succcesfully executed command")
-	tests.Info("Output: %+q", outs.Bytes())
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	if errs.String() != "'index.html' saved [11408]\n" {
+		tests.Failed("Should have received scripted output: %+q", errs.Bytes())
+	}
+	tests.Passed("Should have received scripted output")
+}
+
+func TestUnexpectedCommand(t *testing.T) {
+	executor := exectest.New()
+	executor.ExpectRegexp(`^apt-get (install|remove) -y \w+$`).Times(1)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := exec.New(exec.Command("apt-get install -y git"), exec.Using(executor)).Exec(ctx); err != nil {
+		tests.Failed("Should have matched expected command: %+q", err)
+	}
+	tests.Passed("Should have matched expected command")
+
+	err := exec.New(exec.Command("apt-get remove -y git"), exec.Using(executor)).Exec(ctx)
+	if cmdErr, ok := err.(*exec.CommandError); !ok || cmdErr.ExitCode != exectest.UnexpectedExitCode {
+		tests.Failed("Should have failed command exceeding expectation calls: %+q", err)
+	}
+	tests.Passed("Should have failed command exceeding expectation calls")
+
+	if err := executor.Verify(); err == nil {
+		tests.Failed("Should have reported unexpected command")
+	}
+	tests.Passed("Should have reported unexpected command")
+}
+
+func TestOrderedCommands(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("apt-get update")
+	executor.Expect("apt-get install -y git")
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	for _, command := range []string{"apt-get update", "apt-get install -y git"} {
+		if err := exec.New(exec.Command(command), exec.Using(executor)).Exec(ctx); err != nil {
+			tests.Failed("Should have matched expected command: %+q", err)
+		}
+	}
+	tests.Passed("Should have matched expected command")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+
+	executor = exectest.New().InOrder()
+	executor.Expect("apt-get update")
+	executor.Expect("apt-get install -y git")
+
+	for _, command := range []string{"apt-get install -y git", "apt-get update", "apt-get update"} {
+		exec.New(exec.Command(command), exec.Using(executor)).Exec(ctx)
+	}
+
+	if err := executor.Verify(); err == nil {
+		tests.Failed("Should have reported commands out of order")
+	}
+	tests.Passed("Should have reported commands out of order")
+}
+
+func TestWriteFileFromChecked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-exec")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	// sha256 of `box`.
+	sum := "26f8567f2569182294c3fa5b9f9cb2270b554eef628b4c149cf82a42888ff4ae"
+	file := filepath.Join(dir, "bin", "box")
+
+	if err := exec.WriteFileFromChecked(ctx, file, strings.NewReader("tampered"), 0644, "", sum); err == nil {
+		tests.Failed("Should have failed to write file not matching checksum")
+	}
+	tests.Passed("Should have failed to write file not matching checksum")
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		tests.Failed("Should have left no file in place: %+q", err)
+	}
+
+	if _, err := os.Stat(file + ".box-tmp"); !os.IsNotExist(err) {
+		tests.Failed("Should have removed temporary file: %+q", err)
+	}
+	tests.Passed("Should have left no file in place")
+
+	if err := exec.WriteFileFromChecked(ctx, file, strings.NewReader("box"), 0644, "", sum); err != nil {
+		tests.Failed("Should have succcesfully written file matching checksum: %+q", err)
+	}
+
+	if data, err := ioutil.ReadFile(file); err != nil || string(data) != "box" {
+		tests.Failed("Should have moved file into place: %q %+q", data, err)
+	}
+	tests.Passed("Should have succcesfully written file matching checksum")
+
+	if err := exec.WriteFileFromChecked(ctx, file, strings.NewReader("box"), 0644, "", "not-a-sum"); err == nil {
+		tests.Failed("Should have refused invalid checksum")
+	}
+	tests.Passed("Should have refused invalid checksum")
+}
+
+func TestUpdateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-exec")
+	if err != nil {
+		tests.Failed("Should have succcesfully created directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	file := filepath.Join(dir, "certs", "ca.pem")
+
+	for index, step := range []struct {
+		data    string
+		updated bool
+	}{{"ca", true}, {"ca", false}, {"renewed ca", true}} {
+		updated, err := exec.UpdateFile(ctx, file, []byte(step.data), 0644, "")
+		if err != nil || updated != step.updated {
+			tests.Failed("Should have reported update %d of file as %t: %t %+q", index, step.updated, updated, err)
+		}
+
+		if data, err := ioutil.ReadFile(file); err != nil || string(data) != step.data {
+			tests.Failed("Should have written file contents: %q %+q", data, err)
+		}
+	}
+	tests.Passed("Should have only updated file with changed contents")
+
+	if _, err := os.Stat(file + ".box-tmp"); !os.IsNotExist(err) {
+		tests.Failed("Should have removed temporary file: %+q", err)
+	}
+	tests.Passed("Should have removed temporary file")
 }
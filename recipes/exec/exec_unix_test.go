@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package exec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/tests"
+)
+
+func TestCancelledCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-exec")
+	if err != nil {
+		tests.Failed("Should have created temporary directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pidFile := filepath.Join(dir, "sleep.pid")
+
+	// The shell ignores SIGTERM, which requires escalating to SIGKILL to stop it.
+	sleepCmd := exec.New(exec.Command("trap '' TERM; sleep 30 & echo $! > "+pidFile+"; wait"), exec.Sync(), exec.GracePeriod(200*time.Millisecond))
+	ctx, cn := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cn()
+
+	start := time.Now()
+	err = sleepCmd.Exec(ctx)
+	if err == nil {
+		tests.Failed("Should have terminated command on context cancellation")
+	}
+	tests.Passed("Should have terminated command on context cancellation")
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		tests.Failed("Should have terminated command within grace period: %s", elapsed)
+	}
+	tests.Passed("Should have terminated command within grace period")
+
+	if cmdErr, ok := err.(*exec.CommandError); !ok || cmdErr.Err != exec.ErrCommandCancelled {
+		tests.Failed("Should have received cancellation error: %+q", err)
+	}
+	tests.Passed("Should have received cancellation error")
+
+	data, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		tests.Failed("Should have read child process id: %+q", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		tests.Failed("Should have parsed child process id: %+q", err)
+	}
+
+	for i := 0; i < 20 && syscall.Kill(pid, 0) == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if syscall.Kill(pid, 0) == nil {
+		tests.Failed("Should have killed child process %d", pid)
+	}
+	tests.Passed("Should have killed child process")
+}
@@ -0,0 +1,290 @@
+// Package exectest provides a scripted exec.Executor for testing recipes without running
+// commands on the host. Commands are matched against expectations by exact string or
+// regular expression, returning canned output and exit codes, while the executed
+// sequence is recorded for assertions.
+package exectest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// UnexpectedExitCode defines the exit code returned for commands which match no expectation.
+const UnexpectedExitCode = 127
+
+// Call contains the details of a command received by the Executor.
+type Call struct {
+	Command string
+	Envs    map[string]string
+	Stdin   []byte
+}
+
+// Expectation defines the canned response for commands matching it.
+type Expectation struct {
+	exact   string
+	pattern *regexp.Regexp
+	stdout  string
+	stderr  string
+	code    int
+	err     error
+	times   int
+	calls   int
+}
+
+// Stdout sets the output written into the command's stdout.
+func (x *Expectation) Stdout(out string) *Expectation {
+	x.stdout = out
+	return x
+}
+
+// Stderr sets the output written into the command's stderr.
+func (x *Expectation) Stderr(out string) *Expectation {
+	x.stderr = out
+	return x
+}
+
+// Exit sets the exit code returned for the command.
+func (x *Expectation) Exit(code int) *Expectation {
+	x.code = code
+	return x
+}
+
+// Fail sets the error returned for the command, as if the command could not be started.
+func (x *Expectation) Fail(err error) *Expectation {
+	x.err = err
+	return x
+}
+
+// Times sets the number of times the expectation can be matched, after which it is skipped.
+// Expectations without a limit match any number of times, or once if the Executor is InOrder.
+func (x *Expectation) Times(n int) *Expectation {
+	x.times = n
+	return x
+}
+
+// Calls returns the number of times the expectation was matched.
+func (x *Expectation) Calls() int {
+	return x.calls
+}
+
+func (x *Expectation) String() string {
+	if x.pattern != nil {
+		return fmt.Sprintf("/%s/", x.pattern)
+	}
+
+	return fmt.Sprintf("%q", x.exact)
+}
+
+func (x *Expectation) matches(command string) bool {
+	if x.times > 0 && x.calls >= x.times {
+		return false
+	}
+
+	if x.pattern != nil {
+		return x.pattern.MatchString(command)
+	}
+
+	return x.exact == command
+}
+
+//===============================================================================================================
+
+// Executor implements the exec.Executor interface, responding to commands from the list
+// of expectations in the order they were added.
+type Executor struct {
+	ml           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	unexpected   []string
+	allowAll     bool
+	ordered      bool
+	next         int
+}
+
+// New returns a new Executor.
+func New() *Executor {
+	return &Executor{}
+}
+
+// Expect adds an expectation for commands exactly matching the provided command.
+func (e *Executor) Expect(command string) *Expectation {
+	return e.add(&Expectation{exact: command})
+}
+
+// ExpectRegexp adds an expectation for commands matching the provided regular expression.
+func (e *Executor) ExpectRegexp(pattern string) *Expectation {
+	return e.add(&Expectation{pattern: regexp.MustCompile(pattern)})
+}
+
+// AllowUnexpected sets commands matching no expectation to succeed without output, instead
+// of failing with UnexpectedExitCode.
+func (e *Executor) AllowUnexpected() *Executor {
+	e.allowAll = true
+	return e
+}
+
+// InOrder sets commands to only match expectations in the order they were added, each
+// command matching the current expectation or one after it. Expectations passed over are
+// never matched, leaving them to be reported by Verify.
+func (e *Executor) InOrder() *Executor {
+	e.ordered = true
+	return e
+}
+
+func (e *Executor) add(x *Expectation) *Expectation {
+	e.ml.Lock()
+	defer e.ml.Unlock()
+
+	e.expectations = append(e.expectations, x)
+	return x
+}
+
+// Execute implements the exec.Executor interface.
+func (e *Executor) Execute(ctx context.CancelContext, req exec.Request) error {
+	call := Call{Command: CommandLine(req), Envs: req.Envs}
+
+	if req.In != nil {
+		stdin, err := ioutil.ReadAll(req.In)
+		if err != nil {
+			return err
+		}
+
+		call.Stdin = stdin
+	}
+
+	e.ml.Lock()
+	e.calls = append(e.calls, call)
+
+	var found *Expectation
+	for index := e.next; index < len(e.expectations); index++ {
+		x := e.expectations[index]
+		if !x.matches(call.Command) {
+			continue
+		}
+
+		found = x
+		found.calls++
+
+		if e.ordered {
+			e.next = index
+			if x.times == 0 || x.calls >= x.times {
+				e.next++
+			}
+		}
+
+		break
+	}
+
+	if found == nil {
+		e.unexpected = append(e.unexpected, call.Command)
+	}
+
+	allowAll := e.allowAll
+	e.ml.Unlock()
+
+	if found == nil {
+		if allowAll {
+			return nil
+		}
+
+		write(req.Err, fmt.Sprintf("exectest: unexpected command %q\n", call.Command))
+		return &exec.ExitError{Code: UnexpectedExitCode}
+	}
+
+	if found.err != nil {
+		return found.err
+	}
+
+	write(req.Out, found.stdout)
+	write(req.Err, found.stderr)
+
+	if found.code != 0 {
+		return &exec.ExitError{Code: found.code}
+	}
+
+	return nil
+}
+
+// String returns the name of the executor.
+func (e *Executor) String() string {
+	return "exectest"
+}
+
+// Calls returns the list of all commands received by the Executor.
+func (e *Executor) Calls() []Call {
+	e.ml.Lock()
+	defer e.ml.Unlock()
+
+	return append([]Call(nil), e.calls...)
+}
+
+// Executed returns the command lines of all commands received by the Executor in order.
+func (e *Executor) Executed() []string {
+	e.ml.Lock()
+	defer e.ml.Unlock()
+
+	executed := make([]string, 0, len(e.calls))
+	for _, call := range e.calls {
+		executed = append(executed, call.Command)
+	}
+
+	return executed
+}
+
+// Unexpected returns the command lines of all commands which matched no expectation.
+func (e *Executor) Unexpected() []string {
+	e.ml.Lock()
+	defer e.ml.Unlock()
+
+	return append([]string(nil), e.unexpected...)
+}
+
+// Verify returns an error if any expectation was never matched or any command matched
+// no expectation.
+func (e *Executor) Verify() error {
+	e.ml.Lock()
+	defer e.ml.Unlock()
+
+	var problems []string
+
+	for _, x := range e.expectations {
+		if x.calls == 0 {
+			problems = append(problems, fmt.Sprintf("expected command %s was not executed", x))
+		}
+	}
+
+	if !e.allowAll {
+		for _, command := range e.unexpected {
+			problems = append(problems, fmt.Sprintf("unexpected command %q was executed", command))
+		}
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("exectest: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// CommandLine returns the command line of the request, which is the command passed to the
+// shell for requests in the `{binary} -c {command}` form, else the space joined arguments.
+func CommandLine(req exec.Request) string {
+	if len(req.Args) == 3 && req.Args[1] == "-c" {
+		return req.Args[2]
+	}
+
+	return strings.Join(req.Args, " ")
+}
+
+func write(w io.Writer, data string) {
+	if w != nil && data != "" {
+		io.WriteString(w, data)
+	}
+}
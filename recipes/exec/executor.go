@@ -0,0 +1,88 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influx6/faux/context"
+)
+
+// Executor defines the transport through which a Commander runs it's commands, allowing
+// the same recipes to run against the local machine or a remote host.
+type Executor interface {
+	Execute(context.CancelContext, Request) error
+}
+
+// Request defines the command handed to an Executor by a Commander.
+type Request struct {
+	// Args contains the binary to be executed and it's arguments.
+	Args []string
+
+	// Envs contains the environment variables to be set for the command, in addition
+	// to those provided by the Executor.
+	Envs map[string]string
+
+	// CleanEnv sets the command to not inherit the environment of the Executor's process,
+	// except for the variables listed in AllowedEnvs.
+	CleanEnv    bool
+	AllowedEnvs []string
+
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+
+	// GracePeriod sets the duration the command is given to exit after receiving SIGTERM
+	// due to context cancellation before it is killed, it defaults to DefaultGracePeriod.
+	GracePeriod time.Duration
+}
+
+// Environ returns the list of `key=value` pairs of the environment a local process
+// would run with for the request.
+func (r Request) Environ() []string {
+	envs := os.Environ()
+	if r.CleanEnv {
+		envs = allowedEnvs(envs, r.AllowedEnvs)
+	}
+
+	for name, val := range r.Envs {
+		envs = append(envs, fmt.Sprintf("%s=%s", name, val))
+	}
+
+	return envs
+}
+
+// gracePeriod returns the request's grace period or DefaultGracePeriod if not set.
+func (r Request) gracePeriod() time.Duration {
+	if r.GracePeriod <= 0 {
+		return DefaultGracePeriod
+	}
+
+	return r.GracePeriod
+}
+
+var (
+	dml             sync.RWMutex
+	defaultExecutor Executor = LocalExecutor{}
+)
+
+// DefaultExecutor returns the Executor used by Commanders which have no Executor set.
+func DefaultExecutor() Executor {
+	dml.RLock()
+	defer dml.RUnlock()
+	return defaultExecutor
+}
+
+// SetDefaultExecutor sets the Executor used by Commanders which have no Executor set,
+// allowing all recipes to target a remote host. If nil, the LocalExecutor is restored.
+func SetDefaultExecutor(executor Executor) {
+	if executor == nil {
+		executor = LocalExecutor{}
+	}
+
+	dml.Lock()
+	defer dml.Unlock()
+	defaultExecutor = executor
+}
@@ -0,0 +1,165 @@
+// Package facts collects details about a host which provisioners need to decide how to
+// setup box, either locally or through the default executor.
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/faux/context"
+)
+
+// DockerDir defines the directory whose free disk space is collected.
+const DockerDir = "/var/lib/docker"
+
+// init systems detected on a host.
+const (
+	Systemd = "systemd"
+	Upstart = "upstart"
+	OpenRC  = "openrc"
+	SysV    = "sysvinit"
+)
+
+// Script contains the shell script run on the host to collect it's facts, it prints a
+// `KEY=value` pair per line which is parsed by Parse.
+const Script = `echo "KERNEL=$(uname -r)"
+echo "ARCH=$(uname -m)"
+echo "CPUS=$(nproc 2>/dev/null || getconf _NPROCESSORS_ONLN 2>/dev/null)"
+echo "MEMORY_KB=$(awk '/^MemTotal:/ {print $2}' /proc/meminfo 2>/dev/null)"
+dir=` + DockerDir + `; while [ ! -d "$dir" ] && [ "$dir" != "/" ]; do dir=$(dirname "$dir"); done
+echo "DISK_FREE_KB=$(df -Pk "$dir" 2>/dev/null | awk 'NR==2 {print $4}')"
+if [ -d /run/systemd/system ]; then echo "INIT=systemd"
+elif [ -x /sbin/openrc-run ] || [ -x /sbin/openrc ] || [ -d /run/openrc ]; then echo "INIT=openrc"
+elif /sbin/init --version 2>/dev/null | grep -q upstart; then echo "INIT=upstart"
+else echo "INIT=sysvinit"; fi
+if [ -f /sys/fs/cgroup/cgroup.controllers ]; then echo "CGROUP=2"
+elif [ -d /sys/fs/cgroup ]; then echo "CGROUP=1"
+else echo "CGROUP=0"; fi
+for pm in apt-get dnf yum pacman apk zypper; do if command -v $pm >/dev/null 2>&1; then echo "PACKAGE_MANAGER=$pm"; break; fi; done
+if command -v getenforce >/dev/null 2>&1; then echo "SELINUX=$(getenforce 2>/dev/null)"; fi
+if command -v docker >/dev/null 2>&1; then echo "DOCKER=$(docker --version 2>/dev/null)"; fi
+if command -v firewall-cmd >/dev/null 2>&1 && systemctl is-active --quiet firewalld 2>/dev/null; then echo "FIREWALL=firewalld"
+elif command -v ufw >/dev/null 2>&1; then echo "FIREWALL=ufw"
+elif command -v firewall-cmd >/dev/null 2>&1; then echo "FIREWALL=firewalld"
+elif command -v nft >/dev/null 2>&1; then echo "FIREWALL=nftables"; fi`
+
+// SELinux modes reported by getenforce.
+const (
+	SELinuxEnforcing  = "Enforcing"
+	SELinuxPermissive = "Permissive"
+	SELinuxDisabled   = "Disabled"
+)
+
+// firewalls detected on a host, a running firewalld is preferred over ufw.
+const (
+	UFW       = "ufw"
+	Firewalld = "firewalld"
+	Nftables  = "nftables"
+)
+
+// Facts contains the details collected from a host.
+type Facts struct {
+	OS              *osinfo.Info `json:"os_info"`
+	Kernel          string       `json:"kernel"`
+	Arch            string       `json:"arch"`
+	CPUs            int          `json:"cpus"`
+	MemoryBytes     uint64       `json:"memory_bytes"`
+	DockerDiskFree  uint64       `json:"docker_disk_free"`
+	InitSystem      string       `json:"init_system"`
+	CgroupVersion   int          `json:"cgroup_version"`
+	PackageManager  string       `json:"package_manager"`
+	SELinux         string       `json:"selinux"`
+	DockerInstalled bool         `json:"docker_installed"`
+	DockerVersion   string       `json:"docker_version"`
+	Firewall        string       `json:"firewall"`
+}
+
+// Collect returns the facts of the host targeted by the default executor.
+func Collect(ctx context.CancelContext) (*Facts, error) {
+	info, err := osinfo.OSInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var outs bytes.Buffer
+	factsCmd := exec.New(exec.Command(Script), exec.Sync(), exec.Output(&outs))
+
+	if err := factsCmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	facts := Parse(outs.Bytes())
+	facts.OS = info
+
+	return facts, nil
+}
+
+// Parse returns the Facts from the output of Script, ignoring values which could not
+// be collected.
+func Parse(data []byte) *Facts {
+	var facts Facts
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		index := strings.Index(line, "=")
+		if index == -1 {
+			continue
+		}
+
+		key, val := line[:index], strings.TrimSpace(line[index+1:])
+		if val == "" {
+			continue
+		}
+
+		switch key {
+		case "KERNEL":
+			facts.Kernel = val
+		case "ARCH":
+			facts.Arch = val
+		case "CPUS":
+			facts.CPUs, _ = strconv.Atoi(val)
+		case "MEMORY_KB":
+			if kb, err := strconv.ParseUint(val, 10, 64); err == nil {
+				facts.MemoryBytes = kb * 1024
+			}
+		case "DISK_FREE_KB":
+			if kb, err := strconv.ParseUint(val, 10, 64); err == nil {
+				facts.DockerDiskFree = kb * 1024
+			}
+		case "INIT":
+			facts.InitSystem = val
+		case "CGROUP":
+			facts.CgroupVersion, _ = strconv.Atoi(val)
+		case "PACKAGE_MANAGER":
+			facts.PackageManager = val
+		case "SELINUX":
+			facts.SELinux = val
+		case "DOCKER":
+			facts.DockerInstalled = true
+			facts.DockerVersion = dockerVersion(val)
+		case "FIREWALL":
+			facts.Firewall = val
+		}
+	}
+
+	return &facts
+}
+
+// dockerVersion returns the version from the output of `docker --version`, which has
+// the form `Docker version 17.06.0-ce, build 02c1d87`.
+func dockerVersion(out string) string {
+	fields := strings.Fields(out)
+	for index, field := range fields {
+		if field == "version" && index+1 < len(fields) {
+			return strings.TrimSuffix(fields[index+1], ",")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,69 @@
+package facts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/tests"
+)
+
+func TestCollect(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("cat /etc/os-release").Stdout("NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"16.04\"\n")
+	executor.Expect(facts.Script).Stdout(`KERNEL=4.4.0-87-generic
+ARCH=x86_64
+CPUS=4
+MEMORY_KB=8167848
+DISK_FREE_KB=52428800
+INIT=systemd
+CGROUP=1
+PACKAGE_MANAGER=apt-get
+DOCKER=Docker version 17.06.0-ce, build 02c1d87
+FIREWALL=ufw
+`)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	hostFacts, err := facts.Collect(ctx)
+	if err != nil {
+		tests.Failed("Should have succcesfully collected facts: %+q", err)
+	}
+	tests.Passed("Should have succcesfully collected facts")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	if hostFacts.OS.ID != "ubuntu" || hostFacts.Kernel != "4.4.0-87-generic" || hostFacts.Arch != "x86_64" || hostFacts.CPUs != 4 {
+		tests.Failed("Should have collected os and kernel facts: %#v", hostFacts)
+	}
+	tests.Passed("Should have collected os and kernel facts")
+
+	if hostFacts.MemoryBytes != 8167848*1024 || hostFacts.DockerDiskFree != 50*1024*1024*1024 {
+		tests.Failed("Should have collected memory and disk facts: %#v", hostFacts)
+	}
+	tests.Passed("Should have collected memory and disk facts")
+
+	if hostFacts.InitSystem != facts.Systemd || hostFacts.CgroupVersion != 1 || hostFacts.PackageManager != "apt-get" || !hostFacts.DockerInstalled || hostFacts.DockerVersion != "17.06.0-ce" || hostFacts.Firewall != facts.UFW {
+		tests.Failed("Should have collected init and docker facts: %#v", hostFacts)
+	}
+	tests.Passed("Should have collected init and docker facts")
+}
+
+func TestParseMissingFacts(t *testing.T) {
+	hostFacts := facts.Parse([]byte("KERNEL=4.9.0\nCPUS=\nINIT=openrc\nCGROUP=0\n"))
+
+	if hostFacts.CPUs != 0 || hostFacts.DockerInstalled || hostFacts.InitSystem != facts.OpenRC {
+		tests.Failed("Should have ignored missing facts: %#v", hostFacts)
+	}
+	tests.Passed("Should have ignored missing facts")
+}
@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidChecksum = errors.New("Checksum must be a hex encoded sha256 digest")
+)
+
+// updatedMarker is printed by UpdateFile's script when the file was written.
+const updatedMarker = "box-updated"
+
+// sha256Hex matches hex encoded sha256 digests.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FileReader defines an Executor which can read files from it's host directly, without
+// running a command.
+type FileReader interface {
+	ReadFile(ctx context.CancelContext, path string) ([]byte, error)
+}
+
+// ReadFile returns the contents of the file at path on the host targeted by the default
+// executor. Executors implementing FileReader read the file directly, else it is read
+// with `cat`.
+func ReadFile(ctx context.CancelContext, path string) ([]byte, error) {
+	executor := DefaultExecutor()
+	if reader, ok := executor.(FileReader); ok {
+		return reader.ReadFile(ctx, path)
+	}
+
+	var outs bytes.Buffer
+	catCmd := New(Command("cat "+QuotePath(path)), Sync(), Output(&outs), Using(executor))
+
+	if err := catCmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return outs.Bytes(), nil
+}
+
+// FileExists returns true if the file exists on the host targeted by the default executor.
+// The test is prefixed with privilege, e.g `sudo `, as files within directories only
+// readable by root would otherwise be reported missing.
+func FileExists(ctx context.CancelContext, path string, privilege string) (bool, error) {
+	testCmd := New(Command(privilege+"test -e "+QuotePath(path)), Sync())
+
+	if err := testCmd.Exec(ctx); err != nil {
+		if cmdErr, ok := err.(*CommandError); ok && cmdErr.ExitCode == 1 {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// WriteFile writes the data into the file on the host targeted by the default executor,
+// creating it's directory if missing. The file is replaced atomically by writing into a
+// temporary file first. The command is prefixed with privilege, e.g `sudo `, allowing
+// files owned by root to be written.
+func WriteFile(ctx context.CancelContext, file string, data []byte, mode os.FileMode, privilege string) error {
+	return WriteFileFrom(ctx, file, bytes.NewReader(data), mode, privilege)
+}
+
+// WriteFileFrom works like WriteFile but streams the file's contents from the reader,
+// allowing large files to be copied to the host without holding them in memory.
+func WriteFileFrom(ctx context.CancelContext, file string, r io.Reader, mode os.FileMode, privilege string) error {
+	tmp := QuotePath(file + ".box-tmp")
+	script := fmt.Sprintf("mkdir -p %s && cat > %s && chmod %04o %s && mv -f %s %s", QuotePath(path.Dir(file)), tmp, mode.Perm(), tmp, tmp, QuotePath(file))
+
+	writeCmd := New(Command(privilege+"sh -c "+shellQuote(script)), Sync(), Input(r))
+	return writeCmd.Exec(ctx)
+}
+
+// UpdateFile works like WriteFile but leaves the file in place if it already contains the
+// data, returning true if the file was written.
+func UpdateFile(ctx context.CancelContext, file string, data []byte, mode os.FileMode, privilege string) (bool, error) {
+	tmp, target := QuotePath(file+".box-tmp"), QuotePath(file)
+	script := fmt.Sprintf("mkdir -p %s && cat > %s && if cmp -s %s %s; then rm -f %s && chmod %04o %s; else chmod %04o %s && mv -f %s %s && echo %s; fi",
+		QuotePath(path.Dir(file)), tmp, tmp, target, tmp, mode.Perm(), target, mode.Perm(), tmp, tmp, target, updatedMarker)
+
+	var outs bytes.Buffer
+	writeCmd := New(Command(privilege+"sh -c "+shellQuote(script)), Sync(), Input(bytes.NewReader(data)), Output(&outs))
+
+	if err := writeCmd.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(outs.String()) == updatedMarker, nil
+}
+
+// WriteFileFromChecked works like WriteFileFrom but only moves the file into place once
+// the sha256 checksum of the written contents matches sum, removing it otherwise. The
+// checksum is verified on the host, covering the contents as they were received.
+func WriteFileFromChecked(ctx context.CancelContext, file string, r io.Reader, mode os.FileMode, privilege string, sum string) error {
+	if !sha256Hex.MatchString(sum) {
+		return fmt.Errorf("%s: %q", ErrInvalidChecksum, sum)
+	}
+
+	tmp := QuotePath(file + ".box-tmp")
+	check := fmt.Sprintf("echo %s | sha256sum -c - >/dev/null || { rm -f %s; exit 1; }", shellQuote(sum+"  "+file+".box-tmp"), tmp)
+	script := fmt.Sprintf("mkdir -p %s && cat > %s && %s && chmod %04o %s && mv -f %s %s", QuotePath(path.Dir(file)), tmp, check, mode.Perm(), tmp, tmp, QuotePath(file))
+
+	writeCmd := New(Command(privilege+"sh -c "+shellQuote(script)), Sync(), Input(r))
+	return writeCmd.Exec(ctx)
+}
+
+// QuotePath returns the path shell quoted if it contains characters other than those
+// commonly found in file paths, for use within commands run on hosts.
+func QuotePath(path string) string {
+	if path != "" && strings.Trim(path, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_./-") == "" {
+		return path
+	}
+
+	return shellQuote(path)
+}
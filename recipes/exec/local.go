@@ -0,0 +1,95 @@
+package exec
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/influx6/faux/context"
+)
+
+// LocalExecutor implements the Executor interface, running commands on the local machine
+// with os/exec.
+type LocalExecutor struct{}
+
+// Execute runs the provided request as a local process within it's own process group.
+// If the context gets cancelled, the process group receives SIGTERM and if still running
+// after the request's grace period, SIGKILL.
+func (LocalExecutor) Execute(ctx context.CancelContext, req Request) error {
+	cmder := exec.Command(req.Args[0], req.Args[1:]...)
+	cmder.Stderr = req.Err
+	cmder.Stdin = req.In
+	cmder.Stdout = req.Out
+	cmder.Env = req.Environ()
+
+	setProcessGroup(cmder)
+
+	if err := cmder.Start(); err != nil {
+		return err
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+			case <-finished:
+				return
+			}
+
+			terminateProcessGroup(cmder)
+
+			select {
+			case <-time.After(req.gracePeriod()):
+				killProcessGroup(cmder)
+			case <-finished:
+			}
+		}()
+	}
+
+	if err := cmder.Wait(); err != nil {
+		return localExitError(err)
+	}
+
+	if cmder.ProcessState == nil {
+		return nil
+	}
+
+	if !cmder.ProcessState.Success() {
+		return ErrCommandFailed
+	}
+
+	return nil
+}
+
+// ReadFile implements the FileReader interface, reading the file from the local filesystem.
+func (LocalExecutor) ReadFile(ctx context.CancelContext, path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// String returns the name of the executor.
+func (LocalExecutor) String() string {
+	return "local"
+}
+
+// localExitError returns a *ExitError for errors reporting a process's exit status.
+func localExitError(err error) error {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return err
+	}
+
+	if status.Signaled() {
+		return &ExitError{Code: -1, Signal: status.Signal().String()}
+	}
+
+	return &ExitError{Code: status.ExitStatus()}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package exec
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on windows, where processes have no process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills the command's process, as windows has no SIGTERM.
+func terminateProcessGroup(cmd *exec.Cmd) {
+	killProcessGroup(cmd)
+}
+
+// killProcessGroup kills the command's process.
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	cmd.Process.Kill()
+}
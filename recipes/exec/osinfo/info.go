@@ -4,44 +4,74 @@ package osinfo
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/influx6/box/recipes/exec"
 	"github.com/influx6/faux/context"
 )
 
-// OSInfo retrieves the OSRelease details related to the operating system.
+// errors
+var (
+	ErrUnknownOS         = errors.New("Unable to identify operating system")
+	ErrUnterminatedQuote = errors.New("Value has unterminated quote")
+	ErrInvalidKey        = errors.New("Key is not a valid os-release variable name")
+)
+
+// OSReleaseFiles contains the os-release files read in order of preference.
+var OSReleaseFiles = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// OSInfo retrieves the OSRelease details related to the operating system. If no os-release
+// file exists, it falls back to `lsb_release`, `/etc/debian_version`, `/etc/redhat-release`
+// and `/etc/alpine-release` in that order.
 func OSInfo(ctx context.CancelContext) (*Info, error) {
-	if data, err := useETC(ctx); err == nil {
-		return NewInfo(data)
+	for _, file := range OSReleaseFiles {
+		if data, err := exec.ReadFile(ctx, file); err == nil {
+			return NewInfo(data)
+		}
 	}
 
-	data, err := useUsrLib(ctx)
-	if err != nil {
-		return nil, err
+	if data, err := useLSBRelease(ctx); err == nil {
+		if info := NewLSBInfo(data); info.ID != "" {
+			return info, nil
+		}
 	}
 
-	return NewInfo(data)
-}
+	if data, err := exec.ReadFile(ctx, "/etc/debian_version"); err == nil {
+		return &Info{
+			ID:         "debian",
+			Name:       "Debian GNU/Linux",
+			PrettyName: "Debian GNU/Linux " + strings.TrimSpace(string(data)),
+			VersionID:  strings.TrimSpace(string(data)),
+		}, nil
+	}
 
-func useETC(ctx context.CancelContext) ([]byte, error) {
-	var outs bytes.Buffer
-	lsCmd := exec.New(exec.Command("cat /etc/os-release"), exec.Sync(), exec.Output(&outs))
+	if data, err := exec.ReadFile(ctx, "/etc/redhat-release"); err == nil {
+		if info := NewRedhatInfo(data); info.ID != "" {
+			return info, nil
+		}
+	}
 
-	if err := lsCmd.Exec(ctx); err != nil {
-		return nil, err
+	if data, err := exec.ReadFile(ctx, "/etc/alpine-release"); err == nil {
+		return &Info{
+			ID:         "alpine",
+			Name:       "Alpine Linux",
+			PrettyName: "Alpine Linux v" + strings.TrimSpace(string(data)),
+			VersionID:  strings.TrimSpace(string(data)),
+		}, nil
 	}
 
-	return outs.Bytes(), nil
+	return nil, ErrUnknownOS
 }
 
-func useUsrLib(ctx context.CancelContext) ([]byte, error) {
+func useLSBRelease(ctx context.CancelContext) ([]byte, error) {
 	var outs bytes.Buffer
-	lsCmd := exec.New(exec.Command("cat /usr/lib/os-release"), exec.Sync(), exec.Output(&outs))
+	lsbCmd := exec.New(exec.Command("lsb_release -a"), exec.Sync(), exec.Output(&outs))
 
-	if err := lsCmd.Exec(ctx); err != nil {
+	if err := lsbCmd.Exec(ctx); err != nil {
 		return nil, err
 	}
 
@@ -52,21 +82,25 @@ func useUsrLib(ctx context.CancelContext) ([]byte, error) {
 // See http://www.freedesktop.org/software/systemd/man/os-release.html for more details
 
 // Info reflects values in /etc/os-release
-// Values in this struct must always be string
+// Values in this struct must always be string, except Extra
 // or the reflection will not work properly.
 type Info struct {
-	AnsiColor    string `osr:"ANSI_COLOR"`
-	Name         string `osr:"NAME"`
-	Version      string `osr:"VERSION"`
-	Variant      string `osr:"VARIANT"`
-	VariantID    string `osr:"VARIANT_ID"`
-	ID           string `osr:"ID"`
-	IDLike       string `osr:"ID_LIKE"`
-	PrettyName   string `osr:"PRETTY_NAME"`
-	VersionID    string `osr:"VERSION_ID"`
-	HomeURL      string `osr:"HOME_URL"`
-	SupportURL   string `osr:"SUPPORT_URL"`
-	BugReportURL string `osr:"BUG_REPORT_URL"`
+	AnsiColor       string `osr:"ANSI_COLOR"`
+	Name            string `osr:"NAME"`
+	Version         string `osr:"VERSION"`
+	Variant         string `osr:"VARIANT"`
+	VariantID       string `osr:"VARIANT_ID"`
+	ID              string `osr:"ID"`
+	IDLike          string `osr:"ID_LIKE"`
+	PrettyName      string `osr:"PRETTY_NAME"`
+	VersionID       string `osr:"VERSION_ID"`
+	VersionCodename string `osr:"VERSION_CODENAME"`
+	HomeURL         string `osr:"HOME_URL"`
+	SupportURL      string `osr:"SUPPORT_URL"`
+	BugReportURL    string `osr:"BUG_REPORT_URL"`
+
+	// Extra contains the keys which have no corresponding field, like UBUNTU_CODENAME.
+	Extra map[string]string
 }
 
 // NewInfo returns a giving OSRelease instance from the provided content.
@@ -78,25 +112,106 @@ func NewInfo(contents []byte) (*Info, error) {
 	return osr, nil
 }
 
-// ParseInfo attempts to parse the provided data.
+// NewLSBInfo returns a giving OSRelease instance from the output of `lsb_release -a`.
+func NewLSBInfo(contents []byte) *Info {
+	osr := &Info{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		index := strings.Index(scanner.Text(), ":")
+		if index == -1 {
+			continue
+		}
+
+		key, val := scanner.Text()[:index], strings.TrimSpace(scanner.Text()[index+1:])
+		switch key {
+		case "Distributor ID":
+			osr.Name = val
+			osr.ID = strings.ToLower(val)
+		case "Description":
+			osr.PrettyName = val
+		case "Release":
+			osr.VersionID = val
+		case "Codename":
+			osr.VersionCodename = val
+		}
+	}
+
+	return osr
+}
+
+var redhatRelease = regexp.MustCompile(`^(.+?) release ([0-9.]+)(?:\s*\((.+)\))?`)
+
+// NewRedhatInfo returns a giving OSRelease instance from the contents of /etc/redhat-release,
+// which have the form `CentOS release 6.9 (Final)`.
+func NewRedhatInfo(contents []byte) *Info {
+	line := strings.TrimSpace(string(contents))
+
+	matches := redhatRelease.FindStringSubmatch(line)
+	if matches == nil {
+		return &Info{}
+	}
+
+	osr := &Info{
+		Name:            matches[1],
+		PrettyName:      line,
+		VersionID:       matches[2],
+		VersionCodename: strings.ToLower(matches[3]),
+		IDLike:          "rhel fedora",
+	}
+
+	switch {
+	case strings.HasPrefix(matches[1], "CentOS"):
+		osr.ID = "centos"
+	case strings.HasPrefix(matches[1], "Fedora"):
+		osr.ID = "fedora"
+		osr.IDLike = ""
+	case strings.HasPrefix(matches[1], "Rocky"):
+		osr.ID = "rocky"
+	case strings.HasPrefix(matches[1], "Red Hat"):
+		osr.ID = "rhel"
+		osr.IDLike = "fedora"
+	default:
+		osr.ID = strings.ToLower(strings.Fields(matches[1])[0])
+	}
+
+	return osr
+}
+
+// Codename returns the release codename of the operating system. Derivatives like Linux
+// Mint set VERSION_CODENAME to their own release while UBUNTU_CODENAME or DEBIAN_CODENAME
+// name the release they're based on, which are preferred as repositories are published
+// for the latter.
+func (osr *Info) Codename() string {
+	for _, key := range []string{"UBUNTU_CODENAME", "DEBIAN_CODENAME"} {
+		if codename := osr.Extra[key]; codename != "" {
+			return codename
+		}
+	}
+
+	return osr.VersionCodename
+}
+
+// ParseInfo attempts to parse the provided data. Lines which are not valid assignments
+// are skipped as required by the os-release specification.
 func (osr *Info) ParseInfo(osReleaseContents []byte) error {
 	r := bytes.NewReader(osReleaseContents)
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		key, val, err := parseLine(scanner.Text())
-		if err != nil {
+		if err != nil || key == "" {
 			continue
 		}
-		osr.setIfPossible(key, val)
-	}
-	return nil
-}
 
-func stripQuotes(val string) string {
-	if len(val) > 0 && val[0] == '"' {
-		return val[1 : len(val)-1]
+		if err := osr.setIfPossible(key, val); err != nil {
+			if osr.Extra == nil {
+				osr.Extra = map[string]string{}
+			}
+
+			osr.Extra[key] = val
+		}
 	}
-	return val
+	return scanner.Err()
 }
 
 func (osr *Info) setIfPossible(key, val string) error {
@@ -113,16 +228,77 @@ func (osr *Info) setIfPossible(key, val string) error {
 	return fmt.Errorf("Couldn't set key %s, no corresponding struct field found", key)
 }
 
+var validKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseLine parses a `KEY=value` line, returning an empty key for blank lines and comments.
 func parseLine(osrLine string) (string, string, error) {
-	if osrLine == "" {
+	line := strings.TrimSpace(osrLine)
+	if line == "" || strings.HasPrefix(line, "#") {
 		return "", "", nil
 	}
 
-	vals := strings.Split(osrLine, "=")
-	if len(vals) != 2 {
-		return "", "", fmt.Errorf("Expected %s to split by '=' char into two strings, instead got %d strings", osrLine, len(vals))
+	index := strings.Index(line, "=")
+	if index == -1 {
+		return "", "", fmt.Errorf("Expected %s to contain an '=' char", osrLine)
+	}
+
+	key := line[:index]
+	if !validKey.MatchString(key) {
+		return "", "", ErrInvalidKey
+	}
+
+	val, err := unquote(line[index+1:])
+	if err != nil {
+		return "", "", err
 	}
-	key := vals[0]
-	val := stripQuotes(vals[1])
+
 	return key, val, nil
 }
+
+// unquote returns the value with shell quoting and escapes removed, following the
+// rules used for os-release values: single quoted text is literal, double quoted text
+// supports escaping of `$`, `"`, `\` and "`", and unquoted text supports escaping of
+// any character.
+func unquote(raw string) (string, error) {
+	var val bytes.Buffer
+	var quote byte
+
+	for i := 0; i < len(raw); i++ {
+		char := raw[i]
+
+		switch {
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+				continue
+			}
+
+		case quote == '"':
+			if char == '"' {
+				quote = 0
+				continue
+			}
+
+			if char == '\\' && i+1 < len(raw) && strings.IndexByte("$\"\\`", raw[i+1]) != -1 {
+				i++
+				char = raw[i]
+			}
+
+		case char == '\'' || char == '"':
+			quote = char
+			continue
+
+		case char == '\\' && i+1 < len(raw):
+			i++
+			char = raw[i]
+		}
+
+		val.WriteByte(char)
+	}
+
+	if quote != 0 {
+		return "", ErrUnterminatedQuote
+	}
+
+	return val.String(), nil
+}
@@ -0,0 +1,128 @@
+package osinfo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/faux/tests"
+)
+
+var osRelease = []byte(`# generated by build
+NAME="Ubuntu"
+VERSION='16.04.3 LTS (Xenial Xerus)'
+ID=ubuntu
+ID_LIKE=debian
+PRETTY_NAME="Ubuntu \"Xenial\" \$16.04"
+VERSION_ID="16.04"
+HOME_URL="http://www.ubuntu.com/?ref=os&x=1"
+VERSION_CODENAME=xenial
+UBUNTU_CODENAME=xenial
+invalid line
+BROKEN="unterminated
+`)
+
+var mintRelease = []byte(`NAME="Linux Mint"
+VERSION="21 (Vanessa)"
+ID=linuxmint
+ID_LIKE="ubuntu debian"
+PRETTY_NAME="Linux Mint 21"
+VERSION_ID="21"
+HOME_URL="https://www.linuxmint.com/"
+VERSION_CODENAME=vanessa
+UBUNTU_CODENAME=jammy
+`)
+
+var lmdeRelease = []byte(`PRETTY_NAME="LMDE 6 (faye)"
+NAME="LMDE"
+VERSION_ID="6"
+VERSION="6 (faye)"
+VERSION_CODENAME=faye
+ID=linuxmint
+HOME_URL="https://www.linuxmint.com/"
+ID_LIKE=debian
+DEBIAN_CODENAME=bookworm
+`)
+
+func TestParseInfo(t *testing.T) {
+	info, err := osinfo.NewInfo(osRelease)
+	if err != nil {
+		tests.Failed("Should have succcesfully parsed os-release: %+q", err)
+	}
+	tests.Passed("Should have succcesfully parsed os-release")
+
+	if info.Name != "Ubuntu" || info.Version != "16.04.3 LTS (Xenial Xerus)" || info.VersionID != "16.04" {
+		tests.Failed("Should have unquoted values: %#v", info)
+	}
+	tests.Passed("Should have unquoted values")
+
+	if info.PrettyName != `Ubuntu "Xenial" $16.04` {
+		tests.Failed("Should have unescaped values: %q", info.PrettyName)
+	}
+	tests.Passed("Should have unescaped values")
+
+	if info.HomeURL != "http://www.ubuntu.com/?ref=os&x=1" {
+		tests.Failed("Should have kept '=' within values: %q", info.HomeURL)
+	}
+	tests.Passed("Should have kept '=' within values")
+
+	if info.Codename() != "xenial" || info.Extra["UBUNTU_CODENAME"] != "xenial" {
+		tests.Failed("Should have kept extra keys: %#v", info.Extra)
+	}
+	tests.Passed("Should have kept extra keys")
+
+	if _, ok := info.Extra["BROKEN"]; ok {
+		tests.Failed("Should have skipped values with unterminated quotes")
+	}
+	tests.Passed("Should have skipped values with unterminated quotes")
+}
+
+func TestDerivativeCodename(t *testing.T) {
+	for release, expected := range map[string]string{
+		string(mintRelease): "jammy",
+		string(lmdeRelease): "bookworm",
+	} {
+		info, err := osinfo.NewInfo([]byte(release))
+		if err != nil {
+			tests.Failed("Should have succcesfully parsed os-release: %+q", err)
+		}
+
+		if info.Codename() != expected {
+			tests.Failed("Should have used codename %q of base release for %q: %q", expected, info.PrettyName, info.Codename())
+		}
+	}
+	tests.Passed("Should have used codename of base release for derivatives")
+}
+
+func TestOSInfoFallback(t *testing.T) {
+	executor := exectest.New().InOrder()
+	for _, command := range []string{"cat /etc/os-release", "cat /usr/lib/os-release", "lsb_release -a", "cat /etc/debian_version"} {
+		executor.Expect(command).Exit(1)
+	}
+	executor.Expect("cat /etc/redhat-release").Stdout("CentOS release 6.9 (Final)\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	info, err := osinfo.OSInfo(ctx)
+	if err != nil {
+		tests.Failed("Should have succcesfully retrieved os info: %+q", err)
+	}
+	tests.Passed("Should have succcesfully retrieved os info")
+
+	if info.ID != "centos" || info.VersionID != "6.9" || info.VersionCodename != "final" {
+		tests.Failed("Should have parsed redhat-release: %#v", info)
+	}
+	tests.Passed("Should have parsed redhat-release")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have tried fallbacks in order: %+q", err)
+	}
+	tests.Passed("Should have tried fallbacks in order")
+}
@@ -0,0 +1,42 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrNoPrivilege = errors.New("Unable to gain root privileges, neither doas nor sudo exist")
+)
+
+// PrivilegeCommand contains the command which reports how the user gains root privileges,
+// printing `root` if already root, else `doas` or `sudo` if either exists.
+const PrivilegeCommand = `if [ "$(id -u)" -eq 0 ]; then echo root; elif command -v doas >/dev/null 2>&1; then echo doas; elif command -v sudo >/dev/null 2>&1; then echo sudo; fi`
+
+// Privilege returns the prefix for commands requiring root privileges on the host targeted
+// by the default executor, which is empty if commands already run as root. Recipes take
+// the prefix as their Privilege field, like `sudo `, prefixing only the commands which
+// need it.
+func Privilege(ctx context.CancelContext) (string, error) {
+	var outs bytes.Buffer
+	privCmd := New(Command(PrivilegeCommand), Sync(), Output(&outs))
+
+	if err := privCmd.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	switch strings.TrimSpace(outs.String()) {
+	case "root":
+		return "", nil
+	case "doas":
+		return "doas ", nil
+	case "sudo":
+		return "sudo ", nil
+	}
+
+	return "", ErrNoPrivilege
+}
@@ -0,0 +1,190 @@
+package exec
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted defines the mask which replaces redacted secrets.
+const Redacted = "[REDACTED]"
+
+// minEnvSecretLength defines the minimum length of an environment value before it is
+// redacted from commands and output, to avoid masking common short values.
+const minEnvSecretLength = 4
+
+// DefaultSecretKeys contains the patterns matched against environment variable names
+// to mark their values as secrets.
+var DefaultSecretKeys = []string{
+	`(?i)token`,
+	`(?i)secret`,
+	`(?i)passw(or)?d`,
+	`(?i)api_?key`,
+	`(?i)access_?key`,
+	`(?i)private_?key`,
+	`(?i)credential`,
+	`(?i)auth`,
+}
+
+// DefaultAllowedEnvs contains the environment variables inherited by commands run with
+// a clean environment when no allowlist is provided.
+var DefaultAllowedEnvs = []string{"PATH", "HOME", "USER", "LANG", "TERM", "TMPDIR"}
+
+// Redactor masks secrets found within environment variables, command strings and output
+// of commands before they are emitted into metrics or kept in results.
+type Redactor struct {
+	keys   []*regexp.Regexp
+	values []string
+}
+
+// NewRedactor returns a new Redactor which treats the values of environment variables
+// whose names match any of the key patterns as secrets, in addition to the provided
+// secret values.
+func NewRedactor(keys []string, values ...string) (*Redactor, error) {
+	var rd Redactor
+
+	for _, key := range keys {
+		pattern, err := regexp.Compile(key)
+		if err != nil {
+			return nil, err
+		}
+
+		rd.keys = append(rd.keys, pattern)
+	}
+
+	rd.values = append(rd.values, values...)
+	return &rd, nil
+}
+
+// DefaultRedactor returns a new Redactor using the DefaultSecretKeys patterns.
+func DefaultRedactor() *Redactor {
+	rd, err := NewRedactor(DefaultSecretKeys)
+	if err != nil {
+		panic(err)
+	}
+
+	return rd
+}
+
+// With returns a copy of the Redactor with the provided secret values added.
+func (rd *Redactor) With(values ...string) *Redactor {
+	return &Redactor{
+		keys:   rd.keys,
+		values: append(append([]string(nil), rd.values...), values...),
+	}
+}
+
+// IsSecretKey returns true if the environment variable name matches any of the
+// Redactor's key patterns.
+func (rd *Redactor) IsSecretKey(key string) bool {
+	for _, pattern := range rd.keys {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Learn returns a copy of the Redactor with the values of all secret environment
+// variables within the list of `key=value` pairs added as secret values.
+func (rd *Redactor) Learn(envs []string) *Redactor {
+	var values []string
+
+	for _, env := range envs {
+		key, val := splitEnv(env)
+		if len(val) >= minEnvSecretLength && rd.IsSecretKey(key) {
+			values = append(values, val)
+		}
+	}
+
+	return rd.With(values...)
+}
+
+// Env returns a copy of the list of `key=value` pairs with the values of secret
+// environment variables masked, and known secret values within others replaced.
+func (rd *Redactor) Env(envs []string) []string {
+	redacted := make([]string, 0, len(envs))
+
+	for _, env := range envs {
+		key, val := splitEnv(env)
+		if rd.IsSecretKey(key) {
+			redacted = append(redacted, key+"="+Redacted)
+			continue
+		}
+
+		redacted = append(redacted, key+"="+rd.String(val))
+	}
+
+	return redacted
+}
+
+// String returns the string with all known secret values replaced.
+func (rd *Redactor) String(val string) string {
+	for _, secret := range rd.sortedValues() {
+		val = strings.Replace(val, secret, Redacted, -1)
+	}
+
+	return val
+}
+
+// Strings returns a copy of the list with all known secret values replaced.
+func (rd *Redactor) Strings(vals []string) []string {
+	redacted := make([]string, len(vals))
+	for index, val := range vals {
+		redacted[index] = rd.String(val)
+	}
+
+	return redacted
+}
+
+// Bytes returns a copy of the bytes with all known secret values replaced.
+func (rd *Redactor) Bytes(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	return []byte(rd.String(string(data)))
+}
+
+// sortedValues returns the non-empty secret values sorted from longest to shortest,
+// ensuring secrets containing other secrets are replaced first.
+func (rd *Redactor) sortedValues() []string {
+	values := make([]string, 0, len(rd.values))
+	for _, val := range rd.values {
+		if val != "" {
+			values = append(values, val)
+		}
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	return values
+}
+
+// allowedEnvs returns the `key=value` pairs whose keys are within the allowed list.
+func allowedEnvs(envs []string, allowed []string) []string {
+	var kept []string
+
+	for _, env := range envs {
+		key, _ := splitEnv(env)
+		for _, allow := range allowed {
+			if key == allow {
+				kept = append(kept, env)
+				break
+			}
+		}
+	}
+
+	return kept
+}
+
+func splitEnv(env string) (string, string) {
+	if index := strings.Index(env, "="); index != -1 {
+		return env[:index], env[index+1:]
+	}
+
+	return env, ""
+}
@@ -0,0 +1,56 @@
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/tests"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := exec.DefaultRedactor().Learn([]string{"GITHUB_TOKEN=ghp_4ll0w3d", "PATH=/usr/bin"}).With("hunter22")
+
+	envs := redactor.Env([]string{"GITHUB_TOKEN=ghp_4ll0w3d", "PATH=/usr/bin", "URL=https://hunter22@box.io"})
+	if envs[0] != "GITHUB_TOKEN="+exec.Redacted || envs[1] != "PATH=/usr/bin" || envs[2] != "URL=https://"+exec.Redacted+"@box.io" {
+		tests.Failed("Should have redacted secret environment values: %+q", envs)
+	}
+	tests.Passed("Should have redacted secret environment values")
+
+	if cmd := redactor.String("curl -H 'Authorization: token ghp_4ll0w3d' -u box:hunter22"); strings.Contains(cmd, "ghp_4ll0w3d") || strings.Contains(cmd, "hunter22") {
+		tests.Failed("Should have redacted secrets from command: %q", cmd)
+	}
+	tests.Passed("Should have redacted secrets from command")
+}
+
+func TestRedactedCommand(t *testing.T) {
+	os.Setenv("BOX_LEAKED_VALUE", "leaked")
+	defer os.Unsetenv("BOX_LEAKED_VALUE")
+
+	var outs bytes.Buffer
+	envCmd := exec.New(exec.Command("echo \"$API_TOKEN hunter22\" && env"), exec.Sync(), exec.Output(&outs),
+		exec.Envs(map[string]string{"API_TOKEN": "s3cr3tvalue"}), exec.Secrets("hunter22"), exec.CleanEnv())
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := envCmd.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully executed command: %+q", err)
+	}
+	tests.Passed("Should have succcesfully executed command")
+
+	if strings.Contains(outs.String(), "BOX_LEAKED_VALUE") {
+		tests.Failed("Should have run command with clean environment: %q", outs.String())
+	}
+	tests.Passed("Should have run command with clean environment")
+
+	stdout := string(envCmd.Result.Stdout)
+	if strings.Contains(stdout, "s3cr3tvalue") || strings.Contains(stdout, "hunter22") {
+		tests.Failed("Should have redacted secrets from command output: %q", stdout)
+	}
+	tests.Passed("Should have redacted secrets from command output")
+}
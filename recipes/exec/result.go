@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTailSize defines the default number of bytes of stdout and stderr kept by a
+// Commander for it's Result.
+const DefaultTailSize = 4096
+
+// ExitError is returned by an Executor when a command ran but did not exit successfully.
+type ExitError struct {
+	// Code contains the exit code of the command, or -1 if the command was terminated by a signal.
+	Code int
+
+	// Signal contains the name of the signal which terminated the command if any.
+	Signal string
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return fmt.Sprintf("Command terminated by signal %s", e.Signal)
+	}
+
+	return fmt.Sprintf("Command exited with status %d", e.Code)
+}
+
+// Result contains the details of a command executed by a Commander.
+type Result struct {
+	Command  string
+	Executor string
+	ExitCode int
+	Signal   string
+	Duration time.Duration
+
+	// Stdout and Stderr contain the last bytes written by the command into it's
+	// stdout and stderr, bounded by the Commander's TailSize.
+	Stdout []byte
+	Stderr []byte
+}
+
+// Success returns true if the command exited successfully.
+func (r Result) Success() bool {
+	return r.ExitCode == 0 && r.Signal == ""
+}
+
+// CommandError is returned by Commander.Exec when a command fails to start or exit successfully.
+type CommandError struct {
+	Result
+
+	// Err contains the underline error returned by the Executor.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("Command %q failed: %s", e.Command, e.Err)
+}
+
+// Report returns a multiline report of the failed command, it's exit status and output
+// suitable for display to users.
+func (e *CommandError) Report() string {
+	var report bytes.Buffer
+
+	fmt.Fprintf(&report, "Command:   %s\n", e.Command)
+	if e.Executor != "" {
+		fmt.Fprintf(&report, "Executor:  %s\n", e.Executor)
+	}
+	fmt.Fprintf(&report, "Exit Code: %d\n", e.ExitCode)
+	if e.Signal != "" {
+		fmt.Fprintf(&report, "Signal:    %s\n", e.Signal)
+	}
+	fmt.Fprintf(&report, "Duration:  %s\n", e.Duration)
+	fmt.Fprintf(&report, "Error:     %s\n", e.Err)
+
+	if len(e.Stderr) != 0 {
+		fmt.Fprintf(&report, "Stderr:\n%s\n", indent(e.Stderr))
+	}
+
+	if len(e.Stdout) != 0 {
+		fmt.Fprintf(&report, "Stdout:\n%s\n", indent(e.Stdout))
+	}
+
+	return report.String()
+}
+
+func indent(data []byte) string {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for index, line := range lines {
+		lines[index] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+//===============================================================================================================
+
+// tailBuffer implements the io.Writer interface, keeping only the last max bytes written to it.
+type tailBuffer struct {
+	max  int
+	data []byte
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write implements the io.Writer interface.
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	if t.max <= 0 {
+		return len(p), nil
+	}
+
+	t.data = append(t.data, p...)
+	if over := len(t.data) - t.max; over > 0 {
+		t.data = append(t.data[:0], t.data[over:]...)
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns a copy of the bytes held by the buffer.
+func (t *tailBuffer) Bytes() []byte {
+	return append([]byte(nil), t.data...)
+}
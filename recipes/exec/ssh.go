@@ -0,0 +1,572 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/faux/context"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// errors
+var (
+	ErrNoSSHAuth          = errors.New("No ssh key file or agent was provided for authentication")
+	ErrNoHostKeyPinning   = errors.New("No host key fingerprints or known_hosts file was provided for verification")
+	ErrHostKeyMismatch    = errors.New("Host key does not match any pinned fingerprint")
+	ErrHostKeyUnavailable = errors.New("Host key was not presented by host")
+	ErrKnownHostMismatch  = errors.New("Host key does not match the key listed in known_hosts")
+	ErrInvalidEnvName     = errors.New("Environment variable name must only contain letters, digits and _")
+)
+
+// pidMarker prefixes the line through which remote commands report the id of their
+// process group on stderr, allowing them to be terminated on cancellation.
+const pidMarker = "box-pid:"
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SSHConfig defines the details used to connect and authenticate with a remote host.
+type SSHConfig struct {
+	// User sets the user to login as.
+	User string
+
+	// KeyFiles sets the paths of private keys to authenticate with.
+	KeyFiles []string
+
+	// UseAgent sets the ssh-agent found through SSH_AUTH_SOCK to be used for authentication.
+	UseAgent bool
+
+	// Fingerprints sets the SHA256 fingerprints of the host keys accepted for the host.
+	Fingerprints []string
+
+	// KnownHostsFile sets the known_hosts file to verify the host key against when no
+	// Fingerprints are provided.
+	KnownHostsFile string
+
+	// Timeout sets the timeout for establishing the connection.
+	Timeout time.Duration
+}
+
+// ClientConfig returns the ssh.ClientConfig for the SSHConfig, with the closer of the
+// connection to the ssh-agent, which must be closed once the config is no longer used.
+func (sc SSHConfig) ClientConfig() (*ssh.ClientConfig, io.Closer, error) {
+	return sc.clientConfig(nil)
+}
+
+// clientConfig returns the ssh.ClientConfig for the SSHConfig, recording the key the
+// connection authenticates with into used if not nil.
+func (sc SSHConfig) clientConfig(used *usedKey) (*ssh.ClientConfig, io.Closer, error) {
+	var auths []ssh.AuthMethod
+	var agentConn io.Closer = nopCloser{}
+
+	if len(sc.KeyFiles) != 0 {
+		signers := make([]ssh.Signer, 0, len(sc.KeyFiles))
+
+		for _, keyFile := range sc.KeyFiles {
+			data, err := ioutil.ReadFile(keyFile)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			signer, err := ssh.ParsePrivateKey(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to parse private key %q: %+q", keyFile, err)
+			}
+
+			signers = append(signers, used.wrap(signer))
+		}
+
+		auths = append(auths, ssh.PublicKeys(signers...))
+	}
+
+	if sc.UseAgent {
+		if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			agentConn = conn
+			agentClient := agent.NewClient(conn)
+
+			auths = append(auths, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				signers, err := agentClient.Signers()
+				for index, signer := range signers {
+					signers[index] = used.wrap(signer)
+				}
+
+				return signers, err
+			}))
+		}
+	}
+
+	if len(auths) == 0 {
+		return nil, nil, ErrNoSSHAuth
+	}
+
+	hostKeyCallback, err := sc.hostKeyCallback()
+	if err != nil {
+		agentConn.Close()
+		return nil, nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            sc.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sc.Timeout,
+	}, agentConn, nil
+}
+
+func (sc SSHConfig) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if len(sc.Fingerprints) != 0 {
+		return PinnedHostKeys(sc.Fingerprints...), nil
+	}
+
+	if sc.KnownHostsFile != "" {
+		return knownhosts.New(sc.KnownHostsFile)
+	}
+
+	return nil, ErrNoHostKeyPinning
+}
+
+// PinnedHostKeys returns a ssh.HostKeyCallback which only accepts host keys matching
+// one of the provided SHA256 fingerprints.
+func PinnedHostKeys(fingerprints ...string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		keyPrint := ssh.FingerprintSHA256(key)
+		for _, fingerprint := range fingerprints {
+			if fingerprint == keyPrint {
+				return nil
+			}
+		}
+
+		return ErrHostKeyMismatch
+	}
+}
+
+// HostKeyFingerprint connects to the ssh server at the provided address, returning the
+// SHA256 fingerprint of it's host key without authenticating.
+func HostKeyFingerprint(addr string, timeout time.Duration) (string, error) {
+	key, err := HostKey(addr, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	return ssh.FingerprintSHA256(key), nil
+}
+
+// HostKey connects to the ssh server at the provided address, returning it's host key
+// without authenticating.
+func HostKey(addr string, timeout time.Duration) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+
+	config := &ssh.ClientConfig{
+		Timeout: timeout,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return ErrHostKeyUnavailable
+		},
+	}
+
+	client, err := ssh.Dial("tcp", addr, config)
+	if err == nil {
+		client.Close()
+	}
+
+	if hostKey == nil {
+		if err == nil {
+			err = ErrHostKeyUnavailable
+		}
+
+		return nil, err
+	}
+
+	return hostKey, nil
+}
+
+// KnownHostKey returns true if the known_hosts file lists the key for the address, and
+// false if the file is missing or does not list the address. An error is returned if the
+// file lists other keys for the address, as a changed host key may mean the connection is
+// intercepted.
+func KnownHostKey(file string, addr string, key ssh.PublicKey) (bool, error) {
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// The address is preferred over the remote address when matching lines, which
+	// only needs to be in host:port format.
+	remote := &net.TCPAddr{IP: net.IPv4zero}
+
+	switch err := callback(addr, remote, key).(type) {
+	case nil:
+		return true, nil
+	case *knownhosts.KeyError:
+		if len(err.Want) == 0 {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%s: %s", ErrKnownHostMismatch, err.Want[0].String())
+	default:
+		return false, err
+	}
+}
+
+//===============================================================================================================
+
+// SSHExecutor implements the Executor interface, running commands on a remote host
+// through an ssh connection.
+type SSHExecutor struct {
+	client    *ssh.Client
+	agentConn io.Closer
+	authKey   ssh.PublicKey
+}
+
+// DialSSH connects to the ssh server at the provided address, returning a SSHExecutor
+// for the connection.
+func DialSSH(addr string, config SSHConfig) (*SSHExecutor, error) {
+	used := new(usedKey)
+
+	clientConfig, agentConn, err := config.clientConfig(used)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ssh.Dial("tcp", addr, clientConfig)
+	if err != nil {
+		agentConn.Close()
+		return nil, err
+	}
+
+	executor := NewSSHExecutor(client)
+	executor.agentConn = agentConn
+	executor.authKey = used.Key()
+
+	return executor, nil
+}
+
+// NewSSHExecutor returns a new SSHExecutor using the provided ssh client.
+func NewSSHExecutor(client *ssh.Client) *SSHExecutor {
+	return &SSHExecutor{client: client}
+}
+
+// AuthKey returns the public key the connection authenticated with, which is nil for
+// executors not created with DialSSH or connections authenticated otherwise.
+func (se *SSHExecutor) AuthKey() ssh.PublicKey {
+	return se.authKey
+}
+
+// Close closes the underline ssh connection and the connection to the ssh-agent.
+func (se *SSHExecutor) Close() error {
+	if se.agentConn != nil {
+		se.agentConn.Close()
+	}
+
+	return se.client.Close()
+}
+
+// Execute runs the provided request in a new ssh session. As many ssh servers ignore
+// signals sent over the session, the command reports it's process group, which on
+// cancellation receives SIGTERM through a separate session and if still running after
+// the request's grace period, SIGKILL before the session is closed.
+func (se *SSHExecutor) Execute(ctx context.CancelContext, req Request) error {
+	command, err := remoteCommand(req)
+	if err != nil {
+		return err
+	}
+
+	session, err := se.client.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	stderr := &pidWriter{w: req.Err}
+
+	session.Stdin = req.In
+	session.Stdout = req.Out
+	session.Stderr = stderr
+
+	// sshd runs the command through the user's shell as leader of a new session, so the
+	// shell's pid, kept by exec, is the id of the command's process group.
+	if err := session.Start(fmt.Sprintf("echo %s$$ >&2; exec %s", pidMarker, command)); err != nil {
+		return err
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+			case <-finished:
+				return
+			}
+
+			se.signal(session, stderr.Pid(), ssh.SIGTERM)
+
+			select {
+			case <-time.After(req.gracePeriod()):
+				se.signal(session, stderr.Pid(), ssh.SIGKILL)
+				session.Close()
+			case <-finished:
+			}
+		}()
+	}
+
+	return sshExitError(session.Wait())
+}
+
+// signal sends the signal to the process group of a running command, falling back to
+// the session if the group is unknown.
+func (se *SSHExecutor) signal(session *ssh.Session, pid int, sig ssh.Signal) {
+	session.Signal(sig)
+
+	if pid <= 0 {
+		return
+	}
+
+	killSession, err := se.client.NewSession()
+	if err != nil {
+		return
+	}
+
+	defer killSession.Close()
+	killSession.Run(fmt.Sprintf("kill -%s -%d", sig, pid))
+}
+
+// String returns the name of the executor with the remote user and address.
+func (se *SSHExecutor) String() string {
+	return fmt.Sprintf("ssh://%s@%s", se.client.User(), se.client.RemoteAddr())
+}
+
+// sshExitError returns a *ExitError for errors reporting a remote command's exit status.
+func sshExitError(err error) error {
+	switch exitErr := err.(type) {
+	case *ssh.ExitError:
+		if exitErr.Signal() != "" {
+			return &ExitError{Code: -1, Signal: "SIG" + exitErr.Signal()}
+		}
+
+		return &ExitError{Code: exitErr.ExitStatus()}
+	case *ssh.ExitMissingError:
+		return &ExitError{Code: -1}
+	}
+
+	return err
+}
+
+// remoteCommand returns the shell quoted command line for the request, prefixed with it's
+// environment variables, as most ssh servers refuse environment variables set on the session.
+// With CleanEnv set, the command runs through `env -i` with only the allowed variables of
+// the remote environment.
+func remoteCommand(req Request) (string, error) {
+	var parts []string
+
+	if req.CleanEnv {
+		parts = append(parts, "env", "-i")
+
+		for _, name := range req.AllowedEnvs {
+			if !envName.MatchString(name) {
+				return "", fmt.Errorf("%s: %q", ErrInvalidEnvName, name)
+			}
+
+			// Expands to nothing if the variable is not set on the remote host.
+			parts = append(parts, fmt.Sprintf(`${%s+"%s=$%s"}`, name, name, name))
+		}
+	}
+
+	if len(req.Envs) != 0 {
+		names := make([]string, 0, len(req.Envs))
+		for name := range req.Envs {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		if !req.CleanEnv {
+			parts = append(parts, "env")
+		}
+
+		for _, name := range names {
+			parts = append(parts, shellQuote(fmt.Sprintf("%s=%s", name, req.Envs[name])))
+		}
+	}
+
+	for _, arg := range req.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// usedKey records the public key of the last signer which signed an authentication
+// request, as the client only signs requests with keys the server accepts.
+type usedKey struct {
+	ml  sync.Mutex
+	key ssh.PublicKey
+}
+
+// Key returns the recorded public key, or nil if no signer signed a request.
+func (uk *usedKey) Key() ssh.PublicKey {
+	uk.ml.Lock()
+	defer uk.ml.Unlock()
+	return uk.key
+}
+
+func (uk *usedKey) set(key ssh.PublicKey) {
+	uk.ml.Lock()
+	defer uk.ml.Unlock()
+	uk.key = key
+}
+
+// wrap returns the signer recording it's public key into the usedKey when it signs,
+// keeping ssh.AlgorithmSigner implementations so rsa keys still sign with sha2.
+func (uk *usedKey) wrap(signer ssh.Signer) ssh.Signer {
+	if uk == nil {
+		return signer
+	}
+
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok {
+		return recordingAlgorithmSigner{AlgorithmSigner: algorithmSigner, used: uk}
+	}
+
+	return recordingSigner{Signer: signer, used: uk}
+}
+
+// recordingSigner implements the ssh.Signer interface, recording it's public key once
+// it signed.
+type recordingSigner struct {
+	ssh.Signer
+	used *usedKey
+}
+
+// Sign implements the ssh.Signer interface.
+func (rs recordingSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signature, err := rs.Signer.Sign(rand, data)
+	if err == nil {
+		rs.used.set(rs.PublicKey())
+	}
+
+	return signature, err
+}
+
+// recordingAlgorithmSigner implements the ssh.AlgorithmSigner interface, recording it's
+// public key once it signed.
+type recordingAlgorithmSigner struct {
+	ssh.AlgorithmSigner
+	used *usedKey
+}
+
+// Sign implements the ssh.Signer interface.
+func (rs recordingAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signature, err := rs.AlgorithmSigner.Sign(rand, data)
+	if err == nil {
+		rs.used.set(rs.PublicKey())
+	}
+
+	return signature, err
+}
+
+// SignWithAlgorithm implements the ssh.AlgorithmSigner interface.
+func (rs recordingAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	signature, err := rs.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+	if err == nil {
+		rs.used.set(rs.PublicKey())
+	}
+
+	return signature, err
+}
+
+// nopCloser implements the io.Closer interface, doing nothing on Close.
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// pidWriter implements the io.Writer interface, taking the line reporting the process group
+// of a remote command off the start of it's stderr before writing the rest into w.
+type pidWriter struct {
+	w io.Writer
+
+	ml   sync.Mutex
+	pid  int
+	done bool
+	line []byte
+}
+
+// Pid returns the id of the command's process group, or 0 if not yet reported.
+func (pw *pidWriter) Pid() int {
+	pw.ml.Lock()
+	defer pw.ml.Unlock()
+	return pw.pid
+}
+
+// Write implements the io.Writer interface.
+func (pw *pidWriter) Write(b []byte) (int, error) {
+	pw.ml.Lock()
+
+	if pw.done {
+		pw.ml.Unlock()
+		return pw.write(b, len(b))
+	}
+
+	pw.line = append(pw.line, b...)
+
+	index := bytes.IndexByte(pw.line, '\n')
+	if index == -1 {
+		pw.ml.Unlock()
+		return len(b), nil
+	}
+
+	pw.done = true
+
+	rest := pw.line[index+1:]
+	if line := string(pw.line[:index]); strings.HasPrefix(line, pidMarker) {
+		pw.pid, _ = strconv.Atoi(strings.TrimPrefix(line, pidMarker))
+	} else {
+		rest = pw.line
+	}
+
+	pw.line = nil
+	pw.ml.Unlock()
+
+	return pw.write(rest, len(b))
+}
+
+func (pw *pidWriter) write(b []byte, n int) (int, error) {
+	if pw.w == nil || len(b) == 0 {
+		return n, nil
+	}
+
+	if _, err := pw.w.Write(b); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// shellQuote returns the value quoted for safe use as a single posix shell word.
+func shellQuote(val string) string {
+	return "'" + strings.Replace(val, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,338 @@
+//go:build !windows
+// +build !windows
+
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/tests"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSHExecutor(t *testing.T) {
+	server := newSSHServer(t)
+	defer server.Close()
+
+	executor, err := exec.DialSSH(server.Addr(), exec.SSHConfig{
+		User:         "box",
+		KeyFiles:     []string{server.otherKeyFile, server.clientKeyFile},
+		Fingerprints: []string{server.fingerprint},
+		Timeout:      5 * time.Second,
+	})
+	if err != nil {
+		tests.Failed("Should have connected to ssh server: %+q", err)
+	}
+	tests.Passed("Should have connected to ssh server")
+
+	defer executor.Close()
+
+	if key := executor.AuthKey(); key == nil || !bytes.Equal(key.Marshal(), server.clientKey.Marshal()) {
+		tests.Failed("Should have recorded key accepted by ssh server")
+	}
+	tests.Passed("Should have recorded key accepted by ssh server")
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	var outs bytes.Buffer
+	echoCmd := exec.New(exec.Command("echo \"$GREETING\" && cat"), exec.Sync(), exec.Using(executor), exec.Output(&outs),
+		exec.Input(strings.NewReader("from stdin")), exec.Envs(map[string]string{"GREETING": "it's box"}))
+
+	if err := echoCmd.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully executed remote command: %+q", err)
+	}
+	tests.Passed("Should have succcesfully executed remote command")
+
+	if outs.String() != "it's box\nfrom stdin" {
+		tests.Failed("Should have received remote command output: %q", outs.String())
+	}
+	tests.Passed("Should have received remote command output")
+
+	os.Setenv("BOX_SSH_SECRET", "hidden")
+	defer os.Unsetenv("BOX_SSH_SECRET")
+
+	outs.Reset()
+	cleanCmd := exec.New(exec.Command("echo \"$BOX_SSH_SECRET:$GREETING:$HOME\""), exec.Sync(), exec.Using(executor), exec.Output(&outs),
+		exec.Envs(map[string]string{"GREETING": "hi"}), exec.CleanEnv("HOME"))
+
+	if err := cleanCmd.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully executed remote command with clean environment: %+q", err)
+	}
+
+	if outs.String() != ":hi:"+os.Getenv("HOME")+"\n" {
+		tests.Failed("Should have only kept allowed environment variables: %q", outs.String())
+	}
+	tests.Passed("Should have only kept allowed environment variables")
+
+	cancelCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	sleepCmd := exec.New(exec.Command("sleep 30"), exec.Sync(), exec.Using(executor), exec.GracePeriod(15*time.Second))
+	if err := sleepCmd.Exec(cancelCtx); err == nil || time.Since(start) > 10*time.Second {
+		tests.Failed("Should have terminated remote command's process group on cancellation: %+q", err)
+	}
+	tests.Passed("Should have terminated remote command's process group on cancellation")
+
+	exitCmd := exec.New(exec.Command("exit 3"), exec.Sync(), exec.Using(executor))
+	err = exitCmd.Exec(ctx)
+	if err == nil {
+		tests.Failed("Should have failed to execute remote command")
+	}
+	tests.Passed("Should have failed to execute remote command")
+
+	if cmdErr, ok := err.(*exec.CommandError); !ok || cmdErr.ExitCode != 3 {
+		tests.Failed("Should have received remote command exit code: %+q", err)
+	}
+	tests.Passed("Should have received remote command exit code")
+
+	if _, err := exec.DialSSH(server.Addr(), exec.SSHConfig{
+		User:         "box",
+		KeyFiles:     []string{server.clientKeyFile},
+		Fingerprints: []string{"SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"},
+	}); err == nil {
+		tests.Failed("Should have rejected host with unpinned host key")
+	}
+	tests.Passed("Should have rejected host with unpinned host key")
+
+	fingerprint, err := exec.HostKeyFingerprint(server.Addr(), 5*time.Second)
+	if err != nil {
+		tests.Failed("Should have retrieved host key fingerprint: %+q", err)
+	}
+
+	if fingerprint != server.fingerprint {
+		tests.Failed("Should have matching host key fingerprint: %q", fingerprint)
+	}
+	tests.Passed("Should have matching host key fingerprint")
+
+	hostKey, err := exec.HostKey(server.Addr(), 5*time.Second)
+	if err != nil {
+		tests.Failed("Should have retrieved host key: %+q", err)
+	}
+
+	knownHosts := filepath.Join(server.dir, "known_hosts")
+	if known, err := exec.KnownHostKey(knownHosts, server.Addr(), hostKey); err != nil || known {
+		tests.Failed("Should have reported host key as unknown without known_hosts: %t %+q", known, err)
+	}
+	tests.Passed("Should have reported host key as unknown without known_hosts")
+
+	for _, step := range []struct {
+		key   ssh.PublicKey
+		known bool
+	}{{hostKey, true}, {server.clientKey, false}} {
+		line := knownhosts.Line([]string{knownhosts.Normalize(server.Addr())}, step.key) + "\n"
+		if err := ioutil.WriteFile(knownHosts, []byte(line), 0600); err != nil {
+			tests.Failed("Should have written known_hosts: %+q", err)
+		}
+
+		known, err := exec.KnownHostKey(knownHosts, server.Addr(), hostKey)
+		if known != step.known || (err != nil) == step.known {
+			tests.Failed("Should have checked host key against known_hosts: %t %+q", known, err)
+		}
+	}
+	tests.Passed("Should have checked host key against known_hosts")
+}
+
+// sshServer implements a minimal in-process ssh server which runs `exec` requests
+// locally with /bin/sh.
+type sshServer struct {
+	listener      net.Listener
+	config        *ssh.ServerConfig
+	fingerprint   string
+	clientKey     ssh.PublicKey
+	clientKeyFile string
+	otherKeyFile  string
+	dir           string
+}
+
+func newSSHServer(t *testing.T) *sshServer {
+	dir, err := ioutil.TempDir("", "box-ssh")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %+q", err)
+	}
+
+	hostKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %+q", err)
+	}
+
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("failed to create host signer: %+q", err)
+	}
+
+	clientKeyFile := filepath.Join(dir, "id_ecdsa")
+	clientPub := writeClientKey(t, clientKeyFile)
+
+	// A key the server does not accept, which the client offers without signing with it.
+	otherKeyFile := filepath.Join(dir, "id_other")
+	writeClientKey(t, otherKeyFile)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientPub.Marshal()) {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %+q", err)
+	}
+
+	server := &sshServer{
+		listener:      listener,
+		config:        config,
+		fingerprint:   ssh.FingerprintSHA256(hostSigner.PublicKey()),
+		clientKey:     clientPub,
+		clientKeyFile: clientKeyFile,
+		otherKeyFile:  otherKeyFile,
+		dir:           dir,
+	}
+
+	go server.serve()
+	return server
+}
+
+// writeClientKey writes a new private key into the file, returning it's public key.
+func writeClientKey(t *testing.T, file string) ssh.PublicKey {
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key: %+q", err)
+	}
+
+	clientDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatalf("failed to marshal client key: %+q", err)
+	}
+
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: clientDER}), 0600); err != nil {
+		t.Fatalf("failed to write client key: %+q", err)
+	}
+
+	clientPub, err := ssh.NewPublicKey(&clientKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create client public key: %+q", err)
+	}
+
+	return clientPub
+}
+
+func (s *sshServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *sshServer) Close() {
+	s.listener.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *sshServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *sshServer) handle(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go s.session(channel, requests)
+	}
+}
+
+func (s *sshServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+
+		req.Reply(true, nil)
+
+		// Like sshd, run the command as leader of it's own process group.
+		cmd := osexec.Command("/bin/sh", "-c", payload.Command)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+
+		stdin, _ := cmd.StdinPipe()
+		go func() {
+			defer stdin.Close()
+			buf := make([]byte, 1024)
+			for {
+				n, err := channel.Read(buf)
+				if n > 0 {
+					stdin.Write(buf[:n])
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		var status uint32
+		if err := cmd.Run(); err != nil {
+			status = 255
+			if exitErr, ok := err.(*osexec.ExitError); ok {
+				status = uint32(exitErr.Sys().(syscall.WaitStatus).ExitStatus())
+			}
+		}
+
+		exitStatus := make([]byte, 4)
+		binary.BigEndian.PutUint32(exitStatus, status)
+		channel.SendRequest("exit-status", false, exitStatus)
+		return
+	}
+}
@@ -0,0 +1,237 @@
+// Package file implements a resource placing files rendered from templates on hosts,
+// changing them only when their contents differ and notifying other ops of changes.
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/users"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/faux/ops"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// errors
+var (
+	ErrNoPath = errors.New("File resource requires an absolute path")
+)
+
+// BackupSuffix defines the suffix of the backup made of a file before it's replaced.
+const BackupSuffix = ".bak"
+
+// TemplateData contains the data templates are rendered with, available within templates
+// as `.Facts` and `.Vars`, e.g `{{.Facts.CPUs}}` or `{{.Vars.port}}`.
+type TemplateData struct {
+	Facts *facts.Facts
+	Vars  map[string]interface{}
+}
+
+// Resource implements the ops.Op interface, placing a file rendered from a template on the
+// host. The file is compared against the rendered content by checksum and only written,
+// atomically, when they differ, after which Notify is executed.
+type Resource struct {
+	Path string
+
+	// Template contains the go text/template the file is rendered from, referencing
+	// variables missing from Vars fails rendering.
+	Template string
+	Facts    *facts.Facts
+	Vars     map[string]interface{}
+
+	// Mode sets the permissions of the file, it defaults to 0644.
+	Mode os.FileMode
+
+	// Owner and Group set the ownership of the file, if empty it's left as written.
+	Owner string
+	Group string
+
+	// Backup sets the existing file to be copied to it's path with BackupSuffix before
+	// it's replaced.
+	Backup bool
+
+	// DryRun sets the file to only be compared, with Diff receiving the changes.
+	DryRun bool
+
+	// Diff sets the writer a unified diff of the changes is written to.
+	Diff io.Writer
+
+	// Notify sets the op executed once the file's content has changed, like a service
+	// restart. It's not executed in dry-run mode.
+	Notify ops.Op
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Render returns the content of the file rendered from it's template.
+func (r Resource) Render() ([]byte, error) {
+	tmpl, err := template.New(r.Path).Option("missingkey=error").Parse(r.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, TemplateData{Facts: r.Facts, Vars: r.Vars}); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
+
+// Exec executes giving recipe for placing the file.
+func (r Resource) Exec(ctx context.CancelContext) error {
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("%s: %q", ErrNoPath, r.Path)
+	}
+
+	for _, name := range []string{r.Owner, r.Group} {
+		if name == "" {
+			continue
+		}
+
+		if err := users.ValidName(name); err != nil {
+			return err
+		}
+	}
+
+	content, err := r.Render()
+	if err != nil {
+		return err
+	}
+
+	mode := r.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+
+	exists, err := exec.FileExists(ctx, r.Path, r.Privilege)
+	if err != nil {
+		return err
+	}
+
+	changed := true
+	if exists {
+		checksum, err := r.output(ctx, fmt.Sprintf("%ssha256sum %s", r.Privilege, exec.QuotePath(r.Path)))
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		changed = !strings.HasPrefix(checksum, hex.EncodeToString(sum[:])+" ")
+	}
+
+	if changed && r.Diff != nil {
+		var existing string
+		if exists {
+			if existing, err = r.output(ctx, fmt.Sprintf("%scat %s", r.Privilege, exec.QuotePath(r.Path))); err != nil {
+				return err
+			}
+		}
+
+		if err := WriteDiff(r.Diff, r.Path, existing, string(content)); err != nil {
+			return err
+		}
+	}
+
+	if r.DryRun {
+		return nil
+	}
+
+	if changed {
+		if exists && r.Backup {
+			if err := exec.Run(ctx, fmt.Sprintf("%scp -p %s %s", r.Privilege, exec.QuotePath(r.Path), exec.QuotePath(r.Path+BackupSuffix)), r.DoWithCmd); err != nil {
+				return err
+			}
+		}
+
+		if err := exec.WriteFile(ctx, r.Path, content, mode, r.Privilege); err != nil {
+			return err
+		}
+	} else if err := r.ensureMode(ctx, mode); err != nil {
+		return err
+	}
+
+	if r.Owner != "" || r.Group != "" {
+		if err := r.ensureOwner(ctx); err != nil {
+			return err
+		}
+	}
+
+	if changed && r.Notify != nil {
+		return r.Notify.Exec(ctx)
+	}
+
+	return nil
+}
+
+// ensureMode sets the mode of an unchanged file if it differs.
+func (r Resource) ensureMode(ctx context.CancelContext, mode os.FileMode) error {
+	current, err := r.output(ctx, fmt.Sprintf("%sstat -c %%a %s", r.Privilege, exec.QuotePath(r.Path)))
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(current) == fmt.Sprintf("%o", mode.Perm()) {
+		return nil
+	}
+
+	return exec.Run(ctx, fmt.Sprintf("%schmod %04o %s", r.Privilege, mode.Perm(), exec.QuotePath(r.Path)), r.DoWithCmd)
+}
+
+// ensureOwner sets the owner and group of the file if they differ.
+func (r Resource) ensureOwner(ctx context.CancelContext) error {
+	current, err := r.output(ctx, fmt.Sprintf("%sstat -c '%%U:%%G' %s", r.Privilege, exec.QuotePath(r.Path)))
+	if err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(current), ":", 2)
+	owner, group := r.Owner, r.Group
+
+	if owner == "" {
+		owner = parts[0]
+	}
+
+	if group == "" && len(parts) == 2 {
+		group = parts[1]
+	}
+
+	if strings.TrimSpace(current) == owner+":"+group {
+		return nil
+	}
+
+	return exec.Run(ctx, fmt.Sprintf("%schown %s:%s %s", r.Privilege, owner, group, exec.QuotePath(r.Path)), r.DoWithCmd)
+}
+
+func (r Resource) output(ctx context.CancelContext, command string) (string, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(command), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return outs.String(), nil
+}
+
+// WriteDiff writes a unified diff between the existing and new content of the file into
+// the writer.
+func WriteDiff(w io.Writer, path string, existing string, content string) error {
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(existing),
+		B:        difflib.SplitLines(content),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	})
+}
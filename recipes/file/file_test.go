@@ -0,0 +1,184 @@
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/file"
+	fcontext "github.com/influx6/faux/context"
+	"github.com/influx6/faux/tests"
+)
+
+const tmpl = `worker_processes {{.Facts.CPUs}};
+listen {{.Vars.port}};
+`
+
+// notify records if it was executed.
+type notify struct {
+	executed bool
+}
+
+func (n *notify) Exec(ctx fcontext.CancelContext) error {
+	n.executed = true
+	return nil
+}
+
+func TestResource(t *testing.T) {
+	existing := "worker_processes 2;\nlisten 80;\n"
+
+	executor := exectest.New()
+	executor.Expect("sudo test -e /etc/nginx/nginx.conf")
+	executor.Expect("sudo sha256sum /etc/nginx/nginx.conf").Stdout(checksum(existing) + "  /etc/nginx/nginx.conf\n")
+	executor.Expect("sudo cat /etc/nginx/nginx.conf").Stdout(existing)
+	executor.Expect("sudo cp -p /etc/nginx/nginx.conf /etc/nginx/nginx.conf.bak")
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/nginx && cat > /etc/nginx/nginx\.conf\.box-tmp && chmod 0644 `)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	var diff bytes.Buffer
+	var restart notify
+
+	resource := file.Resource{
+		Path:      "/etc/nginx/nginx.conf",
+		Template:  tmpl,
+		Facts:     &facts.Facts{CPUs: 4},
+		Vars:      map[string]interface{}{"port": 8080},
+		Backup:    true,
+		Diff:      &diff,
+		Notify:    &restart,
+		Privilege: "sudo ",
+	}
+
+	if err := resource.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully placed file: %+q", err)
+	}
+	tests.Passed("Should have succcesfully placed file")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	if calls := executor.Calls(); string(calls[len(calls)-1].Stdin) != "worker_processes 4;\nlisten 8080;\n" {
+		tests.Failed("Should have written rendered template: %q", calls[len(calls)-1].Stdin)
+	}
+	tests.Passed("Should have written rendered template")
+
+	if !strings.Contains(diff.String(), "-listen 80;\n") || !strings.Contains(diff.String(), "+listen 8080;\n") {
+		tests.Failed("Should have written diff of changes:\n%s", diff.String())
+	}
+	tests.Passed("Should have written diff of changes")
+
+	if !restart.executed {
+		tests.Failed("Should have notified of changed file")
+	}
+	tests.Passed("Should have notified of changed file")
+}
+
+func TestResourceUnchanged(t *testing.T) {
+	content := "worker_processes 4;\nlisten 8080;\n"
+
+	executor := exectest.New()
+	executor.Expect("test -e /etc/nginx/nginx.conf")
+	executor.Expect("sha256sum /etc/nginx/nginx.conf").Stdout(checksum(content) + "  /etc/nginx/nginx.conf\n")
+	executor.Expect("stat -c %a /etc/nginx/nginx.conf").Stdout("600\n")
+	executor.Expect("chmod 0644 /etc/nginx/nginx.conf")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	var restart notify
+
+	resource := file.Resource{
+		Path:     "/etc/nginx/nginx.conf",
+		Template: tmpl,
+		Facts:    &facts.Facts{CPUs: 4},
+		Vars:     map[string]interface{}{"port": 8080},
+		Notify:   &restart,
+	}
+
+	if err := resource.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully checked file: %+q", err)
+	}
+	tests.Passed("Should have succcesfully checked file")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have only corrected mode of file: %+q", err)
+	}
+	tests.Passed("Should have only corrected mode of file")
+
+	if restart.executed {
+		tests.Failed("Should have not notified of unchanged file")
+	}
+	tests.Passed("Should have not notified of unchanged file")
+}
+
+func TestRenderMissingVar(t *testing.T) {
+	resource := file.Resource{Path: "/etc/app.conf", Template: tmpl, Facts: &facts.Facts{}}
+
+	if _, err := resource.Render(); err == nil {
+		tests.Failed("Should have failed rendering template with missing variable")
+	}
+	tests.Passed("Should have failed rendering template with missing variable")
+}
+
+func TestResourceQuoting(t *testing.T) {
+	content := "worker_processes 4;\nlisten 8080;\n"
+
+	executor := exectest.New().InOrder()
+	executor.Expect("test -e '/etc/my app/app.conf'")
+	executor.Expect("sha256sum '/etc/my app/app.conf'").Stdout(checksum(content) + "  /etc/my app/app.conf\n")
+	executor.Expect("stat -c %a '/etc/my app/app.conf'").Stdout("644\n")
+	executor.Expect("stat -c '%U:%G' '/etc/my app/app.conf'").Stdout("root:root\n")
+	executor.Expect("chown www-data:root '/etc/my app/app.conf'")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	resource := file.Resource{
+		Path:     "/etc/my app/app.conf",
+		Template: tmpl,
+		Facts:    &facts.Facts{CPUs: 4},
+		Vars:     map[string]interface{}{"port": 8080},
+		Owner:    "www-data",
+	}
+
+	if err := resource.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully checked file: %+q", err)
+	}
+	tests.Passed("Should have succcesfully checked file")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have quoted path of file: %+q", err)
+	}
+	tests.Passed("Should have quoted path of file")
+
+	resource.Owner = "www-data; reboot"
+	if err := resource.Exec(ctx); err == nil {
+		tests.Failed("Should have rejected invalid owner")
+	}
+	tests.Passed("Should have rejected invalid owner")
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package alpine
+
+import (
+	"github.com/influx6/box"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/faux/ops"
+)
+
+var (
+	_ = box.RegisterJSON("linux/alpine", func() ops.Op {
+		return &alpineProvisioner{}
+	})
+)
+
+// alpineProvisioner implements ops.Op interface and contains necessary procedures to provision a
+// alpine linux vm/system for app deployment with box.
+type alpineProvisioner struct {
+	Info  osinfo.Info `json:"os_info"`
+	Facts facts.Facts `json:"facts"`
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	// Only releases within the enabled repositories can be installed.
+	DockerVersion string `json:"docker_version"`
+}
+
+func (alp *alpineProvisioner) Exec(ctx context.CancelContext) error {
+	constraint, err := dockerapt.ParseConstraint(alp.DockerVersion)
+	if err != nil {
+		return err
+	}
+
+	// Alpine ships without sudo, find out how commands get root privileges.
+	privilege, err := Privilege(ctx)
+	if err != nil {
+		return err
+	}
+
+	apk := packages.Apk{Privilege: privilege}
+
+	// Enable the community repository which provides docker.
+	if err := (CommunityRepository{Privilege: privilege}).Exec(ctx); err != nil {
+		return err
+	}
+
+	// Update apk index
+	if err := apk.Update(ctx); err != nil {
+		return err
+	}
+
+	// Pin docker to the highest release in the repositories matching the constraint.
+	pkgs := Packages
+	if len(constraint) != 0 {
+		if pkgs, err = PinnedPackages(ctx, constraint); err != nil {
+			return err
+		}
+	}
+
+	// Attempt to install necessary packages packages.
+	if err := apk.Install(ctx, pkgs...); err != nil {
+		return err
+	}
+
+	// Register docker with OpenRC and start it.
+	if err := (Service{Name: "docker", Privilege: privilege}).Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package alpine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/faux/tests"
+)
+
+func TestAlpineProvisioner(t *testing.T) {
+	expected := []string{
+		PrivilegeCommand,
+		CommunityCommand(""),
+		"apk update",
+		"apk add --no-progress git curl wget openssh docker",
+		"rc-update add docker boot",
+		"rc-service docker start",
+	}
+
+	executor := exectest.New().InOrder()
+	executor.Expect(PrivilegeCommand).Stdout("root\n")
+	for _, command := range expected[1:] {
+		executor.Expect(command)
+	}
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (&alpineProvisioner{}).Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestAlpineProvisionerDockerVersion(t *testing.T) {
+	policy := strings.Join([]string{
+		"docker policy:",
+		"  20.10.24-r2:",
+		"    https://dl-cdn.alpinelinux.org/alpine/v3.17/community",
+		"  23.0.6-r4:",
+		"    https://dl-cdn.alpinelinux.org/alpine/v3.18/community",
+	}, "\n")
+
+	executor := exectest.New().AllowUnexpected()
+	executor.Expect(PrivilegeCommand).Stdout("root\n")
+	executor.Expect("apk policy docker").Stdout(policy)
+	install := executor.Expect("apk add --no-progress git curl wget openssh docker=20.10.24-r2")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (&alpineProvisioner{DockerVersion: "20.10"}).Exec(ctx); err != nil || install.Calls() != 1 {
+		tests.Failed("Should have installed docker release matching constraint: %+q", err)
+	}
+	tests.Passed("Should have installed docker release matching constraint")
+
+	if err := (&alpineProvisioner{DockerVersion: ">=24"}).Exec(ctx); err == nil {
+		tests.Failed("Should have failed without docker release matching constraint")
+	}
+	tests.Passed("Should have failed without docker release matching constraint")
+}
+
+func TestAlpinePrivilege(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect(PrivilegeCommand).Stdout("doas\n").Times(1)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if privilege, err := Privilege(ctx); err != nil || privilege != "doas " {
+		tests.Failed("Should have used doas for root privileges: %q %+q", privilege, err)
+	}
+	tests.Passed("Should have used doas for root privileges")
+
+	executor.Expect(PrivilegeCommand).Stdout("\n")
+	if _, err := Privilege(ctx); err != ErrNoPrivilege {
+		tests.Failed("Should have failed without doas or sudo: %+q", err)
+	}
+	tests.Passed("Should have failed without doas or sudo")
+}
@@ -0,0 +1,131 @@
+package alpine
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrNoPrivilege = exec.ErrNoPrivilege
+)
+
+// PrivilegeCommand contains the command which reports how the user gains root privileges.
+const PrivilegeCommand = exec.PrivilegeCommand
+
+// Packages contains the packages installed on alpine systems.
+var Packages = packages.Names("git", "curl", "wget", "openssh", "docker")
+
+// PinnedPackages returns Packages with docker pinned to the highest version within the
+// enabled repositories matching the constraint.
+func PinnedPackages(ctx context.CancelContext, constraint dockerapt.Constraint) ([]packages.Package, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command("apk policy docker"), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	version, err := dockerapt.SelectVersion(PolicyVersions(outs.Bytes()), constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make([]packages.Package, 0, len(Packages))
+	for _, pkg := range Packages {
+		if pkg.Name == "docker" {
+			pkg.Version = version
+		}
+
+		pinned = append(pinned, pkg)
+	}
+
+	return pinned, nil
+}
+
+// PolicyVersions returns the package versions listed by `apk policy`, which lists each
+// version indented by two spaces followed by the repositories providing it.
+func PolicyVersions(policy []byte) []string {
+	var versions []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(policy))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") || !strings.HasSuffix(line, ":") {
+			continue
+		}
+
+		versions = append(versions, strings.TrimSuffix(strings.TrimSpace(line), ":"))
+	}
+
+	return versions
+}
+
+// Privilege returns the prefix for commands requiring root privileges on the host, which
+// is empty if commands already run as root.
+func Privilege(ctx context.CancelContext) (string, error) {
+	return exec.Privilege(ctx)
+}
+
+//===============================================================================================================
+
+// CommunityCommand returns the command uncommenting the community repository of the host's
+// release in /etc/apk/repositories, leaving those of edge and other releases commented.
+// Repositories are named after the major and minor version of the release, e.g `v3.19`
+// for `3.19.1`.
+func CommunityCommand(privilege string) string {
+	return fmt.Sprintf(`. /etc/os-release && %ssed -i -e "s|^#\(.*/v${VERSION_ID%%.*}/community\)\$|\1|" /etc/apk/repositories`, privilege)
+}
+
+// CommunityRepository will run necessary commands to enable the community repository of a alpine system.
+type CommunityRepository struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for uncommenting the community repository in /etc/apk/repositories.
+func (repo CommunityRepository) Exec(ctx context.CancelContext) error {
+	cmd := exec.New(exec.Command(CommunityCommand(repo.Privilege)), exec.Async())
+
+	if repo.DoWithCmd != nil {
+		repo.DoWithCmd(cmd)
+	}
+
+	return cmd.Exec(ctx)
+}
+
+//===============================================================================================================
+
+// Service will run necessary commands to add a service to OpenRC's boot runlevel and start it.
+type Service struct {
+	Name      string
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for registering and starting the service.
+func (svc Service) Exec(ctx context.CancelContext) error {
+	for _, command := range []string{
+		fmt.Sprintf("%src-update add %s boot", svc.Privilege, svc.Name),
+		fmt.Sprintf("%src-service %s start", svc.Privilege, svc.Name),
+	} {
+		cmd := exec.New(exec.Command(command), exec.Async())
+
+		if svc.DoWithCmd != nil {
+			svc.DoWithCmd(cmd)
+		}
+
+		if err := cmd.Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package arch
+
+import (
+	"github.com/influx6/box"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/faux/ops"
+)
+
+var (
+	_ = box.RegisterJSON("linux/arch", func() ops.Op {
+		return &archProvisioner{}
+	})
+)
+
+// archProvisioner implements ops.Op interface and contains necessary procedures to provision a
+// arch linux vm/system for app deployment with box.
+type archProvisioner struct {
+	Info  osinfo.Info `json:"os_info"`
+	Facts facts.Facts `json:"facts"`
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	// Pacman only provides the latest release, which must match the constraint.
+	DockerVersion string `json:"docker_version"`
+}
+
+func (arp *archProvisioner) Exec(ctx context.CancelContext) error {
+	constraint, err := dockerapt.ParseConstraint(arp.DockerVersion)
+	if err != nil {
+		return err
+	}
+
+	// Attempt to install sudo
+	if err := SudoInstaller.Exec(ctx); err != nil {
+		return err
+	}
+
+	pacman := packages.Pacman{Privilege: "sudo "}
+
+	// Sync pacman database, arch does not support partial upgrades hence installed
+	// packages are upgraded as well.
+	if err := pacman.Update(ctx); err != nil {
+		return err
+	}
+
+	// Refuse to install a docker release outside the constraint.
+	if len(constraint) != 0 {
+		version, err := AvailableVersion(ctx, "docker")
+		if err != nil {
+			return err
+		}
+
+		if _, err := dockerapt.SelectVersion([]string{version}, constraint); err != nil {
+			return err
+		}
+	}
+
+	// Attempt to install necessary packages packages.
+	if err := pacman.Install(ctx, Packages...); err != nil {
+		return err
+	}
+
+	// Enable and start docker through systemd.
+	if err := DockerServiceEnable.Exec(ctx); err != nil {
+		return err
+	}
+
+	if err := DockerServiceStart.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
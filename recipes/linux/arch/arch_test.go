@@ -0,0 +1,65 @@
+package arch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/faux/tests"
+)
+
+func TestArchProvisioner(t *testing.T) {
+	expected := []string{
+		"if ! type sudo; then pacman -Syu --noconfirm sudo; fi",
+		"sudo pacman -Syu --noconfirm",
+		"sudo pacman -S --noconfirm --needed git curl wget openssh docker",
+		"sudo systemctl enable docker.service",
+		"sudo systemctl start docker.service",
+	}
+
+	executor := exectest.New().InOrder()
+	for _, command := range expected {
+		executor.Expect(command)
+	}
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (&archProvisioner{}).Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestArchProvisionerDockerVersion(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("if ! type sudo; then pacman -Syu --noconfirm sudo; fi")
+	executor.Expect("sudo pacman -Syu --noconfirm")
+	executor.Expect("pacman -Si docker").Stdout("Repository      : extra\nName            : docker\nVersion         : 1:24.0.7-1\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (&archProvisioner{DockerVersion: "<24"}).Exec(ctx); err == nil {
+		tests.Failed("Should have refused docker release outside constraint")
+	}
+	tests.Passed("Should have refused docker release outside constraint")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have stopped before installing packages: %+q", err)
+	}
+	tests.Passed("Should have stopped before installing packages")
+}
@@ -0,0 +1,49 @@
+package arch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrUnknownVersion = errors.New("Package version not found in pacman repositories")
+)
+
+// Packages contains the packages installed on arch systems.
+var Packages = packages.Names("git", "curl", "wget", "openssh", "docker")
+
+// custom executors.
+var (
+	SudoInstaller       = exec.New(exec.Command("if ! type sudo; then pacman -Syu --noconfirm sudo; fi"))
+	DockerServiceEnable = exec.New(exec.Command("sudo systemctl enable docker.service"))
+	DockerServiceStart  = exec.New(exec.Command("sudo systemctl start docker.service"))
+)
+
+// AvailableVersion returns the version of the package within the synced pacman repositories,
+// as listed in the `Version` field of `pacman -Si`.
+func AvailableVersion(ctx context.CancelContext, name string) (string, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(fmt.Sprintf("pacman -Si %s", name)), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(&outs)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if len(fields) == 2 && strings.TrimSpace(fields[0]) == "Version" {
+			return strings.TrimSpace(fields[1]), nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: %q", ErrUnknownVersion, name)
+}
@@ -0,0 +1,145 @@
+package debian
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/linux/ubuntu"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/faux/ops"
+)
+
+var (
+	_ = box.RegisterJSON("linux/debian", func() ops.Op {
+		return &debianProvisioner{}
+	})
+)
+
+// errors
+var (
+	ErrUnknownRelease     = errors.New("Unable to determine Debian release codename")
+	ErrUnsupportedRelease = errors.New("Debian release is not supported by docker")
+)
+
+// codenames maps Debian major versions to their release codenames, for releases whose
+// os-release lacks VERSION_CODENAME.
+var codenames = map[string]string{
+	"6":  "squeeze",
+	"7":  "wheezy",
+	"8":  "jessie",
+	"9":  "stretch",
+	"10": "buster",
+	"11": "bullseye",
+	"12": "bookworm",
+}
+
+// rolling maps derivatives whose os-release names no debian release, like kali-rolling,
+// to the release docker's documentation recommends installing from.
+var rolling = map[string]string{
+	"kali": "bookworm",
+}
+
+// unsupported contains the releases which docker provides no packages for.
+var unsupported = map[string]bool{
+	"squeeze": true,
+	"wheezy":  true,
+}
+
+// custom package installers
+var (
+	CACertificatesInstall = ubuntu.PkgPartial(ubuntu.PkgCommand("ca-certificates", ubuntu.InstallAction), ubuntu.Debian())
+)
+
+// debianProvisioner implements ops.Op interface and contains necessary procedures to provision a
+// debian linux vm/system, or one derived from it, for app deployment with box.
+type debianProvisioner struct {
+	Info  osinfo.Info `json:"os_info"`
+	Facts facts.Facts `json:"facts"`
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	DockerVersion string `json:"docker_version"`
+}
+
+func (dbp *debianProvisioner) Exec(ctx context.CancelContext) error {
+	codename, err := Codename(dbp.Info)
+	if err != nil {
+		return err
+	}
+
+	if unsupported[codename] {
+		return fmt.Errorf("%s: %q", ErrUnsupportedRelease, codename)
+	}
+
+	// Attempt to install sudo
+	if err := ubuntu.SudoInstaller.Exec(ctx); err != nil {
+		return err
+	}
+
+	// Update apt-get
+	if err := (ubuntu.PackageSourceUpdate{}).Exec(ctx); err != nil {
+		return err
+	}
+
+	// Attempt to install necessary packages packages.
+	for _, install := range []ubuntu.PackagePartial{
+		ubuntu.GitInstall,
+		ubuntu.CurlInstall,
+		ubuntu.WgetInstall,
+		CACertificatesInstall,
+		ubuntu.AptTransportHTTPSInstall,
+	} {
+		if err := install().Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Docker is already installed, skip adding it's repository.
+	if dbp.Facts.DockerInstalled && dockerapt.Satisfies(dbp.Facts.DockerVersion, dbp.DockerVersion) {
+		return nil
+	}
+
+	// Install docker from docker's apt repository after verifying it's key.
+	installer := dockerapt.Installer{
+		Distro:     Distro(dbp.Info),
+		Codename:   codename,
+		Arch:       dbp.Facts.Arch,
+		Constraint: dbp.DockerVersion,
+		Privilege:  "sudo ",
+	}
+
+	return installer.Exec(ctx)
+}
+
+// Codename returns the release codename of the giving os info, deriving it from the
+// major version for older releases.
+func Codename(info osinfo.Info) (string, error) {
+	if codename, ok := rolling[info.ID]; ok {
+		return codename, nil
+	}
+
+	if codename := info.Codename(); codename != "" {
+		return codename, nil
+	}
+
+	major := strings.SplitN(info.VersionID, ".", 2)[0]
+	if codename, ok := codenames[major]; ok {
+		return codename, nil
+	}
+
+	return "", ErrUnknownRelease
+}
+
+// Distro returns the name of the distribution within docker's repository for the giving
+// os info, which is `raspbian` for raspbian and `debian` for all other derivatives.
+func Distro(info osinfo.Info) string {
+	if info.ID == "raspbian" {
+		return "raspbian"
+	}
+
+	return "debian"
+}
@@ -0,0 +1,112 @@
+package debian
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt/dockerapttest"
+	"github.com/influx6/faux/tests"
+)
+
+func TestDebianProvisioner(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("if ! type sudo; then apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y sudo; fi")
+	executor.Expect("sudo apt-get -y update")
+	for _, name := range []string{"git", "curl", "wget", "ca-certificates", "apt-transport-https"} {
+		executor.Expect("DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  " + name)
+	}
+
+	dockerapttest.Expect(executor, "raspbian")
+
+	executor.Expect("sudo sh -c 'mkdir -p /etc/apt/keyrings && cat > /etc/apt/keyrings/docker.gpg.box-tmp && chmod 0644 /etc/apt/keyrings/docker.gpg.box-tmp && mv -f /etc/apt/keyrings/docker.gpg.box-tmp /etc/apt/keyrings/docker.gpg'")
+	executor.Expect("sudo sh -c 'mkdir -p /etc/apt/sources.list.d && cat > /etc/apt/sources.list.d/docker.list.box-tmp && chmod 0644 /etc/apt/sources.list.d/docker.list.box-tmp && mv -f /etc/apt/sources.list.d/docker.list.box-tmp /etc/apt/sources.list.d/docker.list'")
+	executor.Expect("sudo env DEBIAN_FRONTEND=noninteractive apt-get update")
+	executor.Expect("apt-cache madison docker-ce").Stdout(" docker-ce | 17.06.0~ce-0~raspbian | https://download.docker.com/linux/raspbian stretch/stable armhf Packages\n")
+	executor.Expect("sudo env DEBIAN_FRONTEND=noninteractive apt-get install -y docker-ce=17.06.0~ce-0~raspbian")
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' docker-ce").Stdout("install ok installed 17.06.0~ce-0~raspbian")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	provisioner := &debianProvisioner{Info: osinfo.Info{ID: "raspbian", IDLike: "debian", VersionID: "9"}}
+	if err := provisioner.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestDebianProvisionerSkipsInstalledDocker(t *testing.T) {
+	executor := exectest.New().AllowUnexpected()
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	provisioner := &debianProvisioner{
+		Info:  osinfo.Info{ID: "debian", VersionCodename: "buster"},
+		Facts: facts.Facts{DockerInstalled: true},
+	}
+
+	if err := provisioner.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	for _, command := range executor.Executed() {
+		if command == "curl -fsSL https://download.docker.com/linux/debian/gpg" {
+			tests.Failed("Should have skipped adding docker repository")
+		}
+	}
+	tests.Passed("Should have skipped adding docker repository")
+}
+
+func TestCodename(t *testing.T) {
+	kali, err := osinfo.NewInfo([]byte(`PRETTY_NAME="Kali GNU/Linux Rolling"
+NAME="Kali GNU/Linux"
+VERSION_ID="2024.1"
+VERSION="2024.1"
+VERSION_CODENAME=kali-rolling
+ID=kali
+ID_LIKE=debian
+HOME_URL="https://www.kali.org/"
+`))
+	if err != nil {
+		tests.Failed("Should have succcesfully parsed os-release: %+q", err)
+	}
+
+	if codename, err := Codename(*kali); err != nil || codename != "bookworm" {
+		tests.Failed("Should have used bookworm for kali-rolling: %q %+q", codename, err)
+	}
+	tests.Passed("Should have used bookworm for kali-rolling")
+
+	if codename, err := Codename(osinfo.Info{ID: "debian", VersionID: "9"}); err != nil || codename != "stretch" {
+		tests.Failed("Should have derived codename from version: %q %+q", codename, err)
+	}
+	tests.Passed("Should have derived codename from version")
+}
+
+func TestUnsupportedRelease(t *testing.T) {
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	provisioner := &debianProvisioner{Info: osinfo.Info{ID: "debian", VersionID: "7"}}
+	if err := provisioner.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to provision wheezy")
+	}
+	tests.Passed("Should have failed to provision wheezy")
+}
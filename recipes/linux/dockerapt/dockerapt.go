@@ -0,0 +1,264 @@
+// Package dockerapt installs docker-ce from docker's official apt repository, verifying the
+// repository's signing key against a pinned fingerprint and installing a release matching
+// a version constraint.
+package dockerapt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrUnknownCodename     = errors.New("Release codename is required for docker's apt repository")
+	ErrFingerprintMismatch = errors.New("Repository key does not match pinned fingerprint")
+	ErrInvalidConstraint   = errors.New("Invalid version constraint")
+	ErrNoMatchingVersion   = errors.New("No docker-ce version matches constraint")
+	ErrVersionMismatch     = errors.New("Installed docker-ce version does not match selected version")
+)
+
+// DefaultFingerprint contains the fingerprint of the key docker signs it's apt repositories with.
+var DefaultFingerprint = "9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+
+// RepositoryURL defines the url of docker's repositories.
+const RepositoryURL = "https://download.docker.com/linux"
+
+// Keyring defines the path of the keyring containing docker's repository key.
+const Keyring = "/etc/apt/keyrings/docker.gpg"
+
+// SourceList defines the path of the apt source list for docker's repository.
+const SourceList = "/etc/apt/sources.list.d/docker.list"
+
+// gpg commands run on the host reading the armored repository key from stdin. The key is
+// listed by importing it into a temporary home, as `--show-keys` only exists from gpg 2.2.8,
+// missing from ubuntu bionic and debian stretch, and the `show-only` import option is missing
+// from gpg 1.4 as shipped by ubuntu xenial.
+const (
+	ShowKeyCommand = `home=$(mktemp -d) && gpg --batch --quiet --homedir "$home" --import && gpg --batch --homedir "$home" --with-colons --fingerprint --list-keys; status=$?; rm -rf "$home"; exit $status`
+	DearmorCommand = "gpg --batch --dearmor"
+)
+
+// cliRelease defines the first docker release which split the cli into docker-ce-cli.
+const cliRelease = "18.09"
+
+// architectures maps `uname -m` architectures to their debian names.
+var architectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "armhf",
+	"armv6l":  "armhf",
+	"i386":    "i386",
+	"i686":    "i386",
+	"ppc64le": "ppc64el",
+	"s390x":   "s390x",
+}
+
+// Installer implements the ops.Op interface, installing docker-ce from docker's apt
+// repository for the giving distro and release codename.
+type Installer struct {
+	// Distro sets the distribution within docker's repository, like ubuntu, debian or raspbian.
+	Distro   string
+	Codename string
+
+	// Arch sets the architecture of the host as reported by `uname -m`, if empty apt
+	// uses the host's native architecture.
+	Arch string
+
+	// Constraint sets the docker releases allowed to be installed, like `>=20.10 <25`,
+	// the highest matching release is installed. If empty the latest release is installed.
+	Constraint string
+
+	// Fingerprint sets the fingerprint the repository key must match, it defaults to
+	// DefaultFingerprint.
+	Fingerprint string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for installing docker-ce.
+func (in Installer) Exec(ctx context.CancelContext) error {
+	if in.Codename == "" {
+		return ErrUnknownCodename
+	}
+
+	constraint, err := ParseConstraint(in.Constraint)
+	if err != nil {
+		return err
+	}
+
+	apt := packages.Apt{Privilege: in.Privilege, DoWithCmd: in.DoWithCmd}
+	if err := packages.EnsureInstalled(ctx, apt, packages.Package{Name: "gnupg"}); err != nil {
+		return err
+	}
+
+	armored, err := in.output(ctx, fmt.Sprintf("curl -fsSL %s/%s/gpg", RepositoryURL, in.Distro), nil)
+	if err != nil {
+		return err
+	}
+
+	// Verify the key with the host's gpg before trusting it with apt.
+	colons, err := in.output(ctx, ShowKeyCommand, bytes.NewReader(armored))
+	if err != nil {
+		return err
+	}
+
+	if err := VerifyFingerprint(colons, in.fingerprint()); err != nil {
+		return err
+	}
+
+	keyring, err := in.output(ctx, DearmorCommand, bytes.NewReader(armored))
+	if err != nil {
+		return err
+	}
+
+	if err := exec.WriteFile(ctx, Keyring, keyring, 0644, in.Privilege); err != nil {
+		return err
+	}
+
+	if err := exec.WriteFile(ctx, SourceList, []byte(in.SourceLine()+"\n"), 0644, in.Privilege); err != nil {
+		return err
+	}
+
+	if err := apt.Update(ctx); err != nil {
+		return err
+	}
+
+	madison, err := in.output(ctx, "apt-cache madison docker-ce", nil)
+	if err != nil {
+		return err
+	}
+
+	version, err := SelectVersion(MadisonVersions(madison), constraint)
+	if err != nil {
+		return err
+	}
+
+	pkgs := []packages.Package{{Name: "docker-ce", Version: version}}
+	if CompareVersions(UpstreamVersion(version), cliRelease) >= 0 {
+		pkgs = append(pkgs, packages.Package{Name: "docker-ce-cli", Version: version}, packages.Package{Name: "containerd.io"})
+	}
+
+	if err := apt.Install(ctx, pkgs...); err != nil {
+		return err
+	}
+
+	installed, err := apt.InstalledVersion(ctx, "docker-ce")
+	if err != nil {
+		return err
+	}
+
+	if installed != version {
+		return fmt.Errorf("%s: %q, expected %q", ErrVersionMismatch, installed, version)
+	}
+
+	return nil
+}
+
+// SourceLine returns the apt source line for docker's repository.
+func (in Installer) SourceLine() string {
+	options := "signed-by=" + Keyring
+	if arch, ok := architectures[in.Arch]; ok {
+		options = "arch=" + arch + " " + options
+	}
+
+	return fmt.Sprintf("deb [%s] %s/%s %s stable", options, RepositoryURL, in.Distro, in.Codename)
+}
+
+func (in Installer) fingerprint() string {
+	if in.Fingerprint == "" {
+		return DefaultFingerprint
+	}
+
+	return in.Fingerprint
+}
+
+func (in Installer) output(ctx context.CancelContext, command string, stdin io.Reader) ([]byte, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(command), exec.Sync(), exec.Output(&outs))
+
+	if stdin != nil {
+		exec.Input(stdin)(cmd)
+	}
+
+	if in.DoWithCmd != nil {
+		in.DoWithCmd(cmd)
+	}
+
+	if err := cmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return outs.Bytes(), nil
+}
+
+// VerifyFingerprint returns an error unless the primary keys listed by ShowKeyCommand all
+// match the fingerprint. The fingerprint is matched ignoring case and spaces.
+func VerifyFingerprint(colons []byte, fingerprint string) error {
+	fingerprints := PrimaryFingerprints(colons)
+	if len(fingerprints) == 0 {
+		return ErrFingerprintMismatch
+	}
+
+	expected := strings.ToUpper(strings.Replace(fingerprint, " ", "", -1))
+	for _, found := range fingerprints {
+		if strings.ToUpper(found) != expected {
+			return fmt.Errorf("%s: %s", ErrFingerprintMismatch, found)
+		}
+	}
+
+	return nil
+}
+
+// PrimaryFingerprints returns the fingerprints of the primary keys within gpg's colon
+// listing, which are the `fpr` records directly following `pub` records.
+func PrimaryFingerprints(colons []byte) []string {
+	var fingerprints []string
+	var primary bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(colons))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+
+		switch fields[0] {
+		case "pub":
+			primary = true
+		case "fpr":
+			if primary && len(fields) > 9 {
+				fingerprints = append(fingerprints, fields[9])
+			}
+
+			primary = false
+		}
+	}
+
+	return fingerprints
+}
+
+// MadisonVersions returns the package versions listed by `apt-cache madison`, whose lines
+// have the form `docker-ce | 17.06.0~ce-0~ubuntu | https://download.docker.com/linux/ubuntu xenial/stable amd64 Packages`.
+func MadisonVersions(madison []byte) []string {
+	var versions []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(madison))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) < 2 {
+			continue
+		}
+
+		if version := strings.TrimSpace(fields[1]); version != "" {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
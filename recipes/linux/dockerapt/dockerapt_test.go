@@ -0,0 +1,218 @@
+package dockerapt_test
+
+import (
+	"bytes"
+	"context"
+	osexec "os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/linux/dockerapt/dockerapttest"
+	"github.com/influx6/faux/tests"
+)
+
+const madison = ` docker-ce | 5:25.0.1-1~ubuntu.22.04~jammy | https://download.docker.com/linux/ubuntu jammy/stable amd64 Packages
+ docker-ce | 5:24.0.7-1~ubuntu.22.04~jammy | https://download.docker.com/linux/ubuntu jammy/stable amd64 Packages
+ docker-ce | 5:20.10.24~3-0~ubuntu-jammy | https://download.docker.com/linux/ubuntu jammy/stable amd64 Packages
+ docker-ce | 5:20.10.13~3-0~ubuntu-jammy | https://download.docker.com/linux/ubuntu jammy/stable amd64 Packages
+`
+
+const installed = "5:24.0.7-1~ubuntu.22.04~jammy"
+
+func TestInstaller(t *testing.T) {
+	writeKeyring := "sudo sh -c 'mkdir -p /etc/apt/keyrings && cat > /etc/apt/keyrings/docker.gpg.box-tmp && chmod 0644 /etc/apt/keyrings/docker.gpg.box-tmp && mv -f /etc/apt/keyrings/docker.gpg.box-tmp /etc/apt/keyrings/docker.gpg'"
+	writeSource := "sudo sh -c 'mkdir -p /etc/apt/sources.list.d && cat > /etc/apt/sources.list.d/docker.list.box-tmp && chmod 0644 /etc/apt/sources.list.d/docker.list.box-tmp && mv -f /etc/apt/sources.list.d/docker.list.box-tmp /etc/apt/sources.list.d/docker.list'"
+
+	executor := exectest.New()
+	dockerapttest.Expect(executor, "ubuntu")
+	executor.Expect(writeKeyring)
+	executor.Expect(writeSource)
+	executor.Expect("sudo env DEBIAN_FRONTEND=noninteractive apt-get update")
+	executor.Expect("apt-cache madison docker-ce").Stdout(madison)
+	executor.Expect("sudo env DEBIAN_FRONTEND=noninteractive apt-get install -y docker-ce=" + installed + " docker-ce-cli=" + installed + " containerd.io")
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' docker-ce").Stdout("install ok installed " + installed)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	installer := dockerapt.Installer{
+		Distro:     "ubuntu",
+		Codename:   "jammy",
+		Arch:       "x86_64",
+		Constraint: ">=20.10 <25",
+		Privilege:  "sudo ",
+	}
+
+	if err := installer.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully installed docker: %+q", err)
+	}
+	tests.Passed("Should have succcesfully installed docker")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	files := map[string][]byte{}
+	for _, call := range executor.Calls() {
+		files[call.Command] = call.Stdin
+	}
+
+	if string(files[dockerapt.ShowKeyCommand]) != dockerapttest.Key || string(files[dockerapt.DearmorCommand]) != dockerapttest.Key {
+		tests.Failed("Should have passed fetched key to gpg: %q", files[dockerapt.ShowKeyCommand])
+	}
+	tests.Passed("Should have passed fetched key to gpg")
+
+	if string(files[writeKeyring]) != dockerapttest.Keyring {
+		tests.Failed("Should have written dearmored keyring: %q", files[writeKeyring])
+	}
+	tests.Passed("Should have written dearmored keyring")
+
+	source := "deb [arch=amd64 signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/ubuntu jammy stable\n"
+	if string(files[writeSource]) != source {
+		tests.Failed("Should have written apt source for codename: %q", files[writeSource])
+	}
+	tests.Passed("Should have written apt source for codename")
+}
+
+func TestInstallerRejectsUnpinnedKey(t *testing.T) {
+	commands := dockerapttest.Commands("debian")
+
+	executor := exectest.New()
+	dockerapttest.Expect(executor, "debian")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	installer := dockerapt.Installer{Distro: "debian", Codename: "stretch", Fingerprint: "0EBF CD88 9DC8 5822 9FC7  DD38 854A E2D8 8D81 803C", Privilege: "sudo "}
+	if err := installer.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to verify key against pinned fingerprint")
+	}
+	tests.Passed("Should have failed to verify key against pinned fingerprint")
+
+	if executed := executor.Executed(); len(executed) != 3 || executed[2] != commands[2] {
+		tests.Failed("Should have stopped before dearmoring and writing keyring: %+q", executed)
+	}
+	tests.Passed("Should have stopped before dearmoring and writing keyring")
+}
+
+func TestInstallerVerifiesInstalledVersion(t *testing.T) {
+	executor := exectest.New().AllowUnexpected()
+	dockerapttest.Expect(executor, "ubuntu")
+	executor.Expect("apt-cache madison docker-ce").Stdout(madison)
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' docker-ce").Stdout("install ok installed 5:25.0.1-1~ubuntu.22.04~jammy")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	installer := dockerapt.Installer{Distro: "ubuntu", Codename: "jammy", Constraint: "20.10"}
+	if err := installer.Exec(ctx); err == nil {
+		tests.Failed("Should have failed with mismatched installed version")
+	}
+	tests.Passed("Should have failed with mismatched installed version")
+
+	for _, command := range executor.Executed() {
+		if command == "env DEBIAN_FRONTEND=noninteractive apt-get install -y docker-ce=5:20.10.24~3-0~ubuntu-jammy docker-ce-cli=5:20.10.24~3-0~ubuntu-jammy containerd.io" {
+			tests.Passed("Should have installed highest release matching constraint")
+			return
+		}
+	}
+	tests.Failed("Should have installed highest release matching constraint")
+}
+
+func TestVerifyFingerprint(t *testing.T) {
+	if err := dockerapt.VerifyFingerprint([]byte(dockerapttest.Colons), "9dc8 5822 9fc7 dd38 854a  e2d8 8d81 803c 0ebf cd88"); err != nil {
+		tests.Failed("Should have matched primary key fingerprint: %+q", err)
+	}
+	tests.Passed("Should have matched primary key fingerprint")
+
+	if err := dockerapt.VerifyFingerprint([]byte(dockerapttest.Colons), "D3306A018370199E527AE7997EA0A9C3F273FCD8"); err == nil {
+		tests.Failed("Should have ignored subkey fingerprints")
+	}
+	tests.Passed("Should have ignored subkey fingerprints")
+
+	if err := dockerapt.VerifyFingerprint(nil, dockerapttest.Fingerprint); err == nil {
+		tests.Failed("Should have failed without keys")
+	}
+	tests.Passed("Should have failed without keys")
+}
+
+// signingKey contains an armored ed25519 key with the fingerprint signingFingerprint.
+const signingKey = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEatPVNBYJKwYBBAHaRw8BAQdAxBMfAmMKROq2kXsy2h9Kf3Tv9JGGWxdzdQBN
+SlFiLV20GkJveCBUZXN0IDxib3hAZXhhbXBsZS5jb20+iJAEExYIADgWIQS7npBQ
+PvM8CHGEU7G435AuT2YFQQUCatPVNAIbAwULCQgHAgYVCgkICwIEFgIDAQIeAQIX
+gAAKCRC435AuT2YFQX/fAQCBZZ8RAjMe/uZEmJ0qIHJrynYX7UJki3lF1s3R0xjf
+1QEA5j6p7CKsplTtHUkTHOxoigI+i6gcitzm6gVZ14OWQAs=
+=cAII
+-----END PGP PUBLIC KEY BLOCK-----
+`
+
+const signingFingerprint = "BB9E90503EF33C08718453B1B8DF902E4F660541"
+
+func TestShowKeyCommand(t *testing.T) {
+	if _, err := osexec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	var outs bytes.Buffer
+	showCmd := exec.New(exec.Command(dockerapt.ShowKeyCommand), exec.Sync(), exec.Input(strings.NewReader(signingKey)), exec.Output(&outs))
+
+	if err := showCmd.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully listed key with gpg: %+q", err)
+	}
+	tests.Passed("Should have succcesfully listed key with gpg")
+
+	if err := dockerapt.VerifyFingerprint(outs.Bytes(), signingFingerprint); err != nil {
+		tests.Failed("Should have matched fingerprint of listed key: %+q\n%s", err, outs.String())
+	}
+	tests.Passed("Should have matched fingerprint of listed key")
+
+	invalidCmd := exec.New(exec.Command(dockerapt.ShowKeyCommand), exec.Sync(), exec.Input(strings.NewReader("not a key")))
+	if err := invalidCmd.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to list invalid key")
+	}
+	tests.Passed("Should have failed to list invalid key")
+}
+
+func TestConstraint(t *testing.T) {
+	constraint, err := dockerapt.ParseConstraint(">=20.10, <25")
+	if err != nil {
+		tests.Failed("Should have succcesfully parsed constraint: %+q", err)
+	}
+	tests.Passed("Should have succcesfully parsed constraint")
+
+	for version, matches := range map[string]bool{"20.10.7": true, "24.0.7": true, "17.06.0": false, "25.0.1": false, "20.9": false} {
+		if constraint.Match(version) != matches {
+			tests.Failed("Should have matched %q as %t", version, matches)
+		}
+	}
+	tests.Passed("Should have matched versions against constraint")
+
+	if _, err := dockerapt.ParseConstraint(">=latest"); err == nil {
+		tests.Failed("Should have failed to parse invalid constraint")
+	}
+	tests.Passed("Should have failed to parse invalid constraint")
+
+	if !dockerapt.Satisfies("17.06.0-ce", "17.06") || dockerapt.Satisfies("17.06.0-ce", ">=20.10") {
+		tests.Failed("Should have checked release reported by docker --version")
+	}
+	tests.Passed("Should have checked release reported by docker --version")
+}
@@ -0,0 +1,57 @@
+// Package dockerapttest provides a repository key fixture for testing recipes which install
+// docker through dockerapt, scripting the gpg commands run on the host to verify it.
+package dockerapttest
+
+import (
+	"fmt"
+
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+)
+
+// Fingerprint contains the fingerprint of the primary key listed in Colons.
+const Fingerprint = "9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+
+// Key contains the armored repository key returned for the key's url.
+const Key = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mQINBFit2ioBEADhWpZ8/wvZ6hUTiXOwQHXMAlaFHcPH9hAtr4F1y2+OYdbtMuth
+-----END PGP PUBLIC KEY BLOCK-----
+`
+
+// Keyring contains the dearmored key returned by dockerapt.DearmorCommand.
+const Keyring = "\x99\x02\x0d\x04\x58\xad\xda\x2a"
+
+// Colons contains the listing of Key returned by dockerapt.ShowKeyCommand.
+const Colons = `pub:-:4096:1:8D81803C0EBFCD88:1487788586:::-:::scSE::::::23::0:
+fpr:::::::::9DC858229FC7DD38854AE2D88D81803C0EBFCD88:
+uid:-::::1487792064::B5BC2AEFB7A0AFF5DF4D3D5BB1A33F6E1D5D9F8C::Docker Release (CE deb) <docker@docker.com>::::::::::0:
+sub:-:4096:1:7EA0A9C3F273FCD8:1487788586::::::s::::::23:
+fpr:::::::::D3306A018370199E527AE7997EA0A9C3F273FCD8:
+`
+
+// KeyURL returns the url of the repository key of the distro.
+func KeyURL(distro string) string {
+	return fmt.Sprintf("%s/%s/gpg", dockerapt.RepositoryURL, distro)
+}
+
+// Commands returns the commands dockerapt.Installer runs to fetch and verify the repository
+// key of the distro, in the order they're run.
+func Commands(distro string) []string {
+	return []string{
+		"dpkg-query -W -f='${Status} ${Version}' gnupg",
+		"curl -fsSL " + KeyURL(distro),
+		dockerapt.ShowKeyCommand,
+		dockerapt.DearmorCommand,
+	}
+}
+
+// Expect adds expectations to the executor for Commands, responding with the key fixture.
+func Expect(executor *exectest.Executor, distro string) {
+	commands := Commands(distro)
+
+	executor.Expect(commands[0]).Stdout("install ok installed 2.2.27-3ubuntu2.1")
+	executor.Expect(commands[1]).Stdout(Key)
+	executor.Expect(commands[2]).Stdout(Colons)
+	executor.Expect(commands[3]).Stdout(Keyring)
+}
@@ -0,0 +1,199 @@
+package dockerapt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clause defines a single comparison of a version Constraint.
+type clause struct {
+	op      string
+	version string
+}
+
+// Constraint defines a list of version comparisons which must all hold for a version to
+// match, like `>=20.10 <25`.
+type Constraint []clause
+
+// operators supported by constraints, ordered so that longer operators are matched first.
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseConstraint returns the Constraint for a space or comma separated list of comparisons,
+// each an operator followed by a version. Versions without an operator match releases
+// starting with them, so `20.10` matches `20.10.7`. An empty string matches any version.
+func ParseConstraint(constraint string) (Constraint, error) {
+	var parsed Constraint
+
+	for _, field := range strings.Fields(strings.Replace(constraint, ",", " ", -1)) {
+		cl := clause{op: "=", version: field}
+
+		for _, op := range operators {
+			if strings.HasPrefix(field, op) {
+				cl = clause{op: op, version: strings.TrimPrefix(field, op)}
+				break
+			}
+		}
+
+		if cl.op == "==" {
+			cl.op = "="
+		}
+
+		if cl.version == "" || !isDigit(cl.version[0]) {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidConstraint, field)
+		}
+
+		parsed = append(parsed, cl)
+	}
+
+	return parsed, nil
+}
+
+// Match returns true if the version satisfies all comparisons of the constraint.
+func (c Constraint) Match(version string) bool {
+	for _, cl := range c {
+		cmp := CompareVersions(version, cl.version)
+
+		var ok bool
+		switch cl.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "!=":
+			ok = !hasPrefixVersion(version, cl.version)
+		case "=":
+			ok = hasPrefixVersion(version, cl.version)
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the constraint as a space separated list of comparisons.
+func (c Constraint) String() string {
+	fields := make([]string, 0, len(c))
+	for _, cl := range c {
+		fields = append(fields, cl.op+cl.version)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// Satisfies returns true if the docker release, as reported by `docker --version`, matches
+// the constraint. Any release satisfies an empty constraint while invalid constraints are
+// satisfied by none.
+func Satisfies(release string, constraint string) bool {
+	parsed, err := ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+
+	if len(parsed) == 0 {
+		return true
+	}
+
+	return release != "" && parsed.Match(UpstreamVersion(release))
+}
+
+// UpstreamVersion returns the docker release of a docker-ce package version, stripping the
+// epoch and packaging suffixes, e.g `20.10.7` for `5:20.10.7~3-0~ubuntu-focal` and `17.06.0`
+// for `17.06.0~ce-0~ubuntu`.
+func UpstreamVersion(pkgVersion string) string {
+	version := pkgVersion
+	if index := strings.Index(version, ":"); index != -1 {
+		version = version[index+1:]
+	}
+
+	if index := strings.IndexAny(version, "~-"); index != -1 {
+		version = version[:index]
+	}
+
+	return version
+}
+
+// SelectVersion returns the highest of the docker-ce package versions whose release
+// matches the constraint.
+func SelectVersion(pkgVersions []string, constraint Constraint) (string, error) {
+	var matched []string
+	for _, version := range pkgVersions {
+		if constraint.Match(UpstreamVersion(version)) {
+			matched = append(matched, version)
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", fmt.Errorf("%s: %q", ErrNoMatchingVersion, constraint.String())
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return CompareVersions(UpstreamVersion(matched[i]), UpstreamVersion(matched[j])) > 0
+	})
+
+	return matched[0], nil
+}
+
+// CompareVersions compares dot separated versions by their numeric components, returning
+// -1, 0 or 1 if a is lower, equal or higher than b. Missing components are treated as 0.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for index := 0; index < len(as) || index < len(bs); index++ {
+		an, bn := component(as, index), component(bs, index)
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// hasPrefixVersion returns true if the leading components of version equal those of prefix.
+func hasPrefixVersion(version, prefix string) bool {
+	vs, ps := strings.Split(version, "."), strings.Split(prefix, ".")
+	if len(vs) < len(ps) {
+		return false
+	}
+
+	for index := range ps {
+		if component(vs, index) != component(ps, index) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// component returns the numeric value of the leading digits of the version component at
+// index, or 0 if missing.
+func component(parts []string, index int) int {
+	if index >= len(parts) {
+		return 0
+	}
+
+	part := parts[index]
+
+	end := 0
+	for end < len(part) && isDigit(part[end]) {
+		end++
+	}
+
+	value, _ := strconv.Atoi(part[:end])
+	return value
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -0,0 +1,270 @@
+// Package dockerd manages the configuration of the docker daemon, merging typed settings
+// into it's daemon.json without losing settings box does not know about.
+package dockerd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidDaemonConfig = errors.New("Invalid docker daemon configuration")
+	ErrInvalidMirror       = errors.New("Registry mirror must be a http or https url")
+	ErrInvalidRegistry     = errors.New("Insecure registry must be a host, host:port or CIDR")
+	ErrInvalidAddressPool  = errors.New("Address pool must have a CIDR base and a size not smaller than it's prefix")
+	ErrInvalidDaemonHost   = errors.New("Daemon host must be a unix://, tcp:// or fd:// socket")
+)
+
+// DaemonConfigPath defines the path of the docker daemon's configuration file.
+const DaemonConfigPath = "/etc/docker/daemon.json"
+
+// BackupSuffix defines the suffix of the backup made of the configuration before it's replaced.
+const BackupSuffix = ".bak"
+
+// AddressPool defines a pool of networks docker allocates it's networks from, each
+// network of Size taken from Base, e.g base `172.80.0.0/16` with size 24.
+type AddressPool struct {
+	Base string `json:"base"`
+	Size int    `json:"size"`
+}
+
+// DaemonConfig contains the settings of daemon.json managed by box, settings which are
+// not set are left as they are in the existing configuration.
+type DaemonConfig struct {
+	LogDriver           string            `json:"log-driver,omitempty"`
+	LogOpts             map[string]string `json:"log-opts,omitempty"`
+	StorageDriver       string            `json:"storage-driver,omitempty"`
+	RegistryMirrors     []string          `json:"registry-mirrors,omitempty"`
+	InsecureRegistries  []string          `json:"insecure-registries,omitempty"`
+	DefaultAddressPools []AddressPool     `json:"default-address-pools,omitempty"`
+	LiveRestore         *bool             `json:"live-restore,omitempty"`
+
+	// Hosts sets the sockets the daemon listens on, like `tcp://0.0.0.0:2376`.
+	Hosts     []string `json:"hosts,omitempty"`
+	TLSVerify *bool    `json:"tlsverify,omitempty"`
+	TLSCACert string   `json:"tlscacert,omitempty"`
+	TLSCert   string   `json:"tlscert,omitempty"`
+	TLSKey    string   `json:"tlskey,omitempty"`
+}
+
+// Validate returns an error if any of the settings would be rejected by the docker daemon.
+func (dc DaemonConfig) Validate() error {
+	for _, mirror := range dc.RegistryMirrors {
+		parsed, err := url.Parse(mirror)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%s: %q", ErrInvalidMirror, mirror)
+		}
+	}
+
+	for _, registry := range dc.InsecureRegistries {
+		if !validRegistry(registry) {
+			return fmt.Errorf("%s: %q", ErrInvalidRegistry, registry)
+		}
+	}
+
+	for _, host := range dc.Hosts {
+		if !strings.HasPrefix(host, "unix://") && !strings.HasPrefix(host, "tcp://") && !strings.HasPrefix(host, "fd://") {
+			return fmt.Errorf("%s: %q", ErrInvalidDaemonHost, host)
+		}
+	}
+
+	for _, pool := range dc.DefaultAddressPools {
+		_, network, err := net.ParseCIDR(pool.Base)
+		if err != nil {
+			return fmt.Errorf("%s: %q", ErrInvalidAddressPool, pool.Base)
+		}
+
+		prefix, bits := network.Mask.Size()
+		if pool.Size < prefix || pool.Size > bits {
+			return fmt.Errorf("%s: %q with size %d", ErrInvalidAddressPool, pool.Base, pool.Size)
+		}
+	}
+
+	return nil
+}
+
+// validRegistry returns true if the registry is a CIDR, or a host with an optional port.
+func validRegistry(registry string) bool {
+	if _, _, err := net.ParseCIDR(registry); err == nil {
+		return true
+	}
+
+	if strings.Contains(registry, "://") || strings.Contains(registry, "/") || registry == "" {
+		return false
+	}
+
+	host := registry
+	if h, port, err := net.SplitHostPort(registry); err == nil {
+		if port == "" || strings.Trim(port, "0123456789") != "" {
+			return false
+		}
+
+		host = h
+	}
+
+	return host != "" && !strings.ContainsAny(host, " \t")
+}
+
+// Merge returns the existing daemon.json with the settings of the config applied, keeping
+// all other settings. The merged configuration is validated before it's returned, and is
+// formatted with sorted keys so unchanged configurations produce identical output.
+func Merge(existing []byte, config DaemonConfig) ([]byte, error) {
+	merged := map[string]json.RawMessage{}
+
+	if len(bytes.TrimSpace(existing)) != 0 {
+		if err := json.Unmarshal(existing, &merged); err != nil {
+			return nil, fmt.Errorf("%s: %s", ErrInvalidDaemonConfig, err)
+		}
+	}
+
+	settings, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates map[string]json.RawMessage
+	if err := json.Unmarshal(settings, &updates); err != nil {
+		return nil, err
+	}
+
+	for key, value := range updates {
+		merged[key] = value
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the merged configuration to validate both managed settings which were
+	// already present and the ones applied.
+	var result DaemonConfig
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrInvalidDaemonConfig, err)
+	}
+
+	if err := result.Validate(); err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+//===============================================================================================================
+
+// DaemonConfigure implements the ops.Op interface, merging the config into the docker
+// daemon's daemon.json and restarting docker if the file changed. The previous file is
+// kept as a backup, and restored if docker fails to restart with the new configuration.
+type DaemonConfigure struct {
+	Config DaemonConfig
+
+	// Path sets the path of the configuration file, it defaults to DaemonConfigPath.
+	Path string
+
+	// InitSystem sets the init system docker is restarted with, like facts.Systemd.
+	InitSystem string
+
+	// Restart sets docker to be restarted even if daemon.json is unchanged, for changes
+	// to files it references like certificates.
+	Restart bool
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for configuring the docker daemon.
+func (dc DaemonConfigure) Exec(ctx context.CancelContext) error {
+	configPath := dc.Path
+	if configPath == "" {
+		configPath = DaemonConfigPath
+	}
+
+	exists, err := exec.FileExists(ctx, configPath, dc.Privilege)
+	if err != nil {
+		return err
+	}
+
+	var existing []byte
+	if exists {
+		if existing, err = exec.ReadFile(ctx, configPath); err != nil {
+			return err
+		}
+	}
+
+	merged, err := Merge(existing, dc.Config)
+	if err != nil {
+		return err
+	}
+
+	// Nothing changed, docker needs no restart unless requested. The existing file is
+	// compared in the merged format so differences in whitespace and key order are ignored.
+	if exists {
+		current, err := Merge(existing, DaemonConfig{})
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(current, merged) {
+			if dc.Restart {
+				return exec.Run(ctx, RestartCommand(dc.InitSystem, dc.Privilege), dc.DoWithCmd)
+			}
+
+			return nil
+		}
+	}
+
+	backup := configPath + BackupSuffix
+	if exists {
+		if err := exec.Run(ctx, fmt.Sprintf("%scp -p %s %s", dc.Privilege, configPath, backup), dc.DoWithCmd); err != nil {
+			return err
+		}
+	}
+
+	if err := exec.WriteFile(ctx, configPath, merged, 0644, dc.Privilege); err != nil {
+		return err
+	}
+
+	restart := RestartCommand(dc.InitSystem, dc.Privilege)
+	if err := exec.Run(ctx, restart, dc.DoWithCmd); err != nil {
+
+		// Restore the working configuration so docker is not left stopped, which without
+		// a previous file is docker's defaults.
+		restore := fmt.Sprintf("%smv -f %s %s", dc.Privilege, backup, configPath)
+		if !exists {
+			restore = fmt.Sprintf("%srm -f %s", dc.Privilege, configPath)
+		}
+
+		if restoreErr := exec.Run(ctx, restore, dc.DoWithCmd); restoreErr != nil {
+			return err
+		}
+
+		// The restart error is reported, docker failing to start with the restored
+		// configuration as well is not a problem of the new one.
+		exec.Run(ctx, restart, dc.DoWithCmd)
+		return err
+	}
+
+	return nil
+}
+
+// RestartCommand returns the command restarting docker with the init system.
+func RestartCommand(initSystem string, privilege string) string {
+	switch initSystem {
+	case facts.Systemd:
+		return privilege + "systemctl restart docker"
+	case facts.OpenRC:
+		return privilege + "rc-service docker restart"
+	}
+
+	return privilege + "service docker restart"
+}
@@ -0,0 +1,146 @@
+package dockerd_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/dockerd"
+	"github.com/influx6/faux/tests"
+)
+
+func TestMerge(t *testing.T) {
+	liveRestore := true
+	merged, err := dockerd.Merge([]byte(`{"debug": true, "log-driver": "syslog"}`), dockerd.DaemonConfig{
+		LogDriver:           "json-file",
+		LogOpts:             map[string]string{"max-size": "10m"},
+		DefaultAddressPools: []dockerd.AddressPool{{Base: "172.80.0.0/16", Size: 24}},
+		LiveRestore:         &liveRestore,
+	})
+	if err != nil {
+		tests.Failed("Should have succcesfully merged config: %+q", err)
+	}
+	tests.Passed("Should have succcesfully merged config")
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(merged, &config); err != nil {
+		tests.Failed("Should have produced valid json: %+q", err)
+	}
+
+	if config["debug"] != true {
+		tests.Failed("Should have kept unknown settings: %s", merged)
+	}
+	tests.Passed("Should have kept unknown settings")
+
+	if config["log-driver"] != "json-file" || config["live-restore"] != true {
+		tests.Failed("Should have applied settings: %s", merged)
+	}
+	tests.Passed("Should have applied settings")
+
+	if _, err := dockerd.Merge(nil, dockerd.DaemonConfig{
+		DefaultAddressPools: []dockerd.AddressPool{{Base: "172.80.0.0/16", Size: 8}},
+	}); err == nil {
+		tests.Failed("Should have failed to merge address pool larger than it's base")
+	}
+	tests.Passed("Should have failed to merge address pool larger than it's base")
+
+	if _, err := dockerd.Merge([]byte(`{"registry-mirrors": ["mirror.local"]}`), dockerd.DaemonConfig{}); err == nil {
+		tests.Failed("Should have failed to merge invalid existing mirror")
+	}
+	tests.Passed("Should have failed to merge invalid existing mirror")
+}
+
+func TestDaemonConfigure(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo test -e /etc/docker/daemon.json")
+	executor.Expect("cat /etc/docker/daemon.json").Stdout(`{"debug": true}`)
+	executor.Expect("sudo cp -p /etc/docker/daemon.json /etc/docker/daemon.json.bak")
+	executor.Expect("sudo sh -c 'mkdir -p /etc/docker && cat > /etc/docker/daemon.json.box-tmp && chmod 0644 /etc/docker/daemon.json.box-tmp && mv -f /etc/docker/daemon.json.box-tmp /etc/docker/daemon.json'")
+	executor.Expect("sudo systemctl restart docker")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	configure := dockerd.DaemonConfigure{
+		Config:     dockerd.DaemonConfig{InsecureRegistries: []string{"10.0.0.0/8", "registry.local:5000"}},
+		InitSystem: facts.Systemd,
+		Privilege:  "sudo ",
+	}
+
+	if err := configure.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully configured docker: %+q", err)
+	}
+	tests.Passed("Should have succcesfully configured docker")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+
+	written := executor.Calls()[3].Stdin
+	if err := json.Unmarshal(written, &map[string]interface{}{}); err != nil {
+		tests.Failed("Should have written valid json: %q", written)
+	}
+	tests.Passed("Should have written valid json")
+}
+
+func TestDaemonConfigureUnchanged(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("test -e /etc/docker/daemon.json")
+	executor.Expect("cat /etc/docker/daemon.json").Stdout("{\"log-driver\":\"json-file\",   \"debug\":true}")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	configure := dockerd.DaemonConfigure{Config: dockerd.DaemonConfig{LogDriver: "json-file"}}
+	if err := configure.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully configured docker: %+q", err)
+	}
+	tests.Passed("Should have succcesfully configured docker")
+
+	if executed := executor.Executed(); len(executed) != 2 {
+		tests.Failed("Should have left unchanged config and docker alone: %+q", executed)
+	}
+	tests.Passed("Should have left unchanged config and docker alone")
+}
+
+func TestDaemonConfigureRemovesFailedConfig(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo test -e /etc/docker/daemon.json").Exit(1)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/docker && cat > /etc/docker/daemon\.json\.box-tmp `)
+	executor.Expect("sudo systemctl restart docker").Exit(1)
+	executor.Expect("sudo rm -f /etc/docker/daemon.json")
+	restarted := executor.Expect("sudo systemctl restart docker")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	configure := dockerd.DaemonConfigure{
+		Config:     dockerd.DaemonConfig{StorageDriver: "zfs"},
+		InitSystem: facts.Systemd,
+		Privilege:  "sudo ",
+	}
+
+	if err := configure.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to restart docker with new config")
+	}
+	tests.Passed("Should have failed to restart docker with new config")
+
+	if err := executor.Verify(); err != nil || restarted.Calls() != 1 {
+		tests.Failed("Should have removed new config and restarted docker: %+q", err)
+	}
+	tests.Passed("Should have removed new config and restarted docker")
+}
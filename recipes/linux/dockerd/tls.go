@@ -0,0 +1,166 @@
+package dockerd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrUnknownExecStart = errors.New("Unable to determine ExecStart of docker's systemd unit")
+)
+
+// CertsDir defines the directory on the host the docker daemon's certificates are installed into.
+const CertsDir = "/etc/docker/certs"
+
+// TLSAddr defines the address the docker daemon listens on for TLS connections.
+const TLSAddr = "tcp://0.0.0.0:2376"
+
+// SocketAddr defines the unix socket the docker daemon keeps listening on for local clients.
+const SocketAddr = "unix:///var/run/docker.sock"
+
+// HostsOverride defines the systemd drop-in removing the `-H fd://` flag of docker's unit,
+// which dockerd refuses to start with when daemon.json also sets hosts.
+const HostsOverride = "/etc/systemd/system/docker.service.d/box-hosts.conf"
+
+// ShowExecStartCommand contains the command listing the ExecStart of docker's systemd unit.
+const ShowExecStartCommand = "systemctl show -p ExecStart docker"
+
+// TLSConfigure implements the ops.Op interface, installing the CA certificate and the
+// server certificate of the host, and configuring the docker daemon to accept only clients
+// with certificates signed by the CA on TLSAddr. Docker is restarted when it's daemon.json
+// or any of the certificates changed.
+type TLSConfigure struct {
+	// CACert, Cert and Key contain the PEM encoded CA certificate, and the server
+	// certificate and key of the host.
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+
+	// InitSystem sets the init system docker is restarted with, like facts.Systemd.
+	InitSystem string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for securing the docker daemon with TLS.
+func (tc TLSConfigure) Exec(ctx context.CancelContext) error {
+	caFile, certFile, keyFile := path.Join(CertsDir, "ca.pem"), path.Join(CertsDir, "server-cert.pem"), path.Join(CertsDir, "server-key.pem")
+
+	certs := []struct {
+		file string
+		data []byte
+		mode os.FileMode
+	}{
+		{caFile, tc.CACert, 0644},
+		{certFile, tc.Cert, 0644},
+		{keyFile, tc.Key, 0600},
+	}
+
+	var changed bool
+	for _, cert := range certs {
+		updated, err := exec.UpdateFile(ctx, cert.file, cert.data, cert.mode, tc.Privilege)
+		if err != nil {
+			return err
+		}
+
+		changed = changed || updated
+	}
+
+	if tc.InitSystem == facts.Systemd {
+		if err := tc.overrideHosts(ctx); err != nil {
+			return err
+		}
+	}
+
+	verify := true
+	configure := DaemonConfigure{
+		Config: DaemonConfig{
+			Hosts:     []string{SocketAddr, TLSAddr},
+			TLSVerify: &verify,
+			TLSCACert: caFile,
+			TLSCert:   certFile,
+			TLSKey:    keyFile,
+		},
+		InitSystem: tc.InitSystem,
+		Restart:    changed,
+		Privilege:  tc.Privilege,
+		DoWithCmd:  tc.DoWithCmd,
+	}
+
+	return configure.Exec(ctx)
+}
+
+// overrideHosts installs HostsOverride with the ExecStart of docker's unit without it's
+// `-H fd://` flag, reloading systemd if the override changed.
+func (tc TLSConfigure) overrideHosts(ctx context.CancelContext) error {
+	var outs bytes.Buffer
+	showCmd := exec.New(exec.Command(ShowExecStartCommand), exec.Sync(), exec.Output(&outs))
+	if tc.DoWithCmd != nil {
+		tc.DoWithCmd(showCmd)
+	}
+
+	if err := showCmd.Exec(ctx); err != nil {
+		return err
+	}
+
+	execStart, err := ExecStartWithoutHosts(outs.String())
+	if err != nil {
+		return err
+	}
+
+	override := fmt.Sprintf("[Service]\nExecStart=\nExecStart=%s\n", execStart)
+
+	updated, err := exec.UpdateFile(ctx, HostsOverride, []byte(override), 0644, tc.Privilege)
+	if err != nil || !updated {
+		return err
+	}
+
+	return exec.Run(ctx, fmt.Sprintf("%ssystemctl daemon-reload", tc.Privilege), tc.DoWithCmd)
+}
+
+// ExecStartWithoutHosts returns the command line of the ExecStart listed by
+// ShowExecStartCommand, which has the form `ExecStart={ path=/usr/bin/dockerd ;
+// argv[]=/usr/bin/dockerd -H fd:// ; ... }`, with it's `-H fd://` flags removed.
+func ExecStartWithoutHosts(show string) (string, error) {
+	start := strings.Index(show, "argv[]=")
+	if start == -1 {
+		return "", ErrUnknownExecStart
+	}
+
+	argv := show[start+len("argv[]="):]
+	if end := strings.Index(argv, " ;"); end != -1 {
+		argv = argv[:end]
+	}
+
+	args := strings.Fields(argv)
+	if len(args) == 0 {
+		return "", ErrUnknownExecStart
+	}
+
+	kept := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-H=fd://", "--host=fd://":
+			continue
+		case "-H", "--host":
+			if i+1 < len(args) && args[i+1] == "fd://" {
+				i++
+				continue
+			}
+		}
+
+		kept = append(kept, args[i])
+	}
+
+	return strings.Join(kept, " "), nil
+}
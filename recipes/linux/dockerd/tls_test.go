@@ -0,0 +1,125 @@
+package dockerd_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/dockerd"
+	"github.com/influx6/faux/tests"
+)
+
+const execStart = "ExecStart={ path=/usr/bin/dockerd ; argv[]=/usr/bin/dockerd -H fd:// --containerd=/run/containerd/containerd.sock ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }\n"
+
+func TestExecStartWithoutHosts(t *testing.T) {
+	command, err := dockerd.ExecStartWithoutHosts(execStart)
+	if err != nil {
+		tests.Failed("Should have succcesfully parsed ExecStart: %+q", err)
+	}
+	tests.Passed("Should have succcesfully parsed ExecStart")
+
+	if command != "/usr/bin/dockerd --containerd=/run/containerd/containerd.sock" {
+		tests.Failed("Should have removed -H fd:// from ExecStart: %q", command)
+	}
+	tests.Passed("Should have removed -H fd:// from ExecStart")
+
+	command, _ = dockerd.ExecStartWithoutHosts("ExecStart={ path=/usr/bin/dockerd ; argv[]=/usr/bin/dockerd --host=fd:// -H tcp://127.0.0.1:2375 --log-level=warn ; }")
+	if command != "/usr/bin/dockerd -H tcp://127.0.0.1:2375 --log-level=warn" {
+		tests.Failed("Should have kept flags other than -H fd://: %q", command)
+	}
+	tests.Passed("Should have kept flags other than -H fd://")
+
+	if _, err := dockerd.ExecStartWithoutHosts("ExecStart=\n"); err == nil {
+		tests.Failed("Should have failed to parse missing ExecStart")
+	}
+	tests.Passed("Should have failed to parse missing ExecStart")
+}
+
+func TestTLSConfigure(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/docker/certs && cat > /etc/docker/certs/`).Stdout("box-updated\n").Times(3)
+	executor.Expect("systemctl show -p ExecStart docker").Stdout(execStart)
+	override := executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/systemd/system/docker\.service\.d && `).Stdout("box-updated\n")
+	executor.Expect("sudo systemctl daemon-reload")
+	executor.Expect("sudo test -e /etc/docker/daemon.json").Exit(1)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/docker && cat > /etc/docker/daemon\.json\.box-tmp `)
+	restarted := executor.Expect("sudo systemctl restart docker")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	configure := dockerd.TLSConfigure{
+		CACert:     []byte("ca"),
+		Cert:       []byte("cert"),
+		Key:        []byte("key"),
+		InitSystem: facts.Systemd,
+		Privilege:  "sudo ",
+	}
+
+	if err := configure.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully secured docker: %+q", err)
+	}
+	tests.Passed("Should have succcesfully secured docker")
+
+	if err := executor.Verify(); err != nil || restarted.Calls() != 1 {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	var written string
+	for _, call := range executor.Calls() {
+		if strings.Contains(call.Command, "docker.service.d") {
+			written = string(call.Stdin)
+		}
+	}
+
+	if override.Calls() != 1 || written != "[Service]\nExecStart=\nExecStart=/usr/bin/dockerd --containerd=/run/containerd/containerd.sock\n" {
+		tests.Failed("Should have overridden ExecStart of docker's unit: %q", written)
+	}
+	tests.Passed("Should have overridden ExecStart of docker's unit")
+}
+
+func TestTLSConfigureRestartsForCertificates(t *testing.T) {
+	config := `{"hosts": ["unix:///var/run/docker.sock", "tcp://0.0.0.0:2376"], "tlsverify": true,
+		"tlscacert": "/etc/docker/certs/ca.pem", "tlscert": "/etc/docker/certs/server-cert.pem", "tlskey": "/etc/docker/certs/server-key.pem"}`
+
+	executor := exectest.New().InOrder()
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/docker/certs && `).Times(2)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/docker/certs && cat > /etc/docker/certs/server-key\.pem\.box-tmp `).Stdout("box-updated\n")
+	executor.Expect("systemctl show -p ExecStart docker").Stdout(execStart)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/systemd/system/docker\.service\.d && `)
+	executor.Expect("sudo test -e /etc/docker/daemon.json")
+	executor.Expect("cat /etc/docker/daemon.json").Stdout(config)
+	restarted := executor.Expect("sudo systemctl restart docker")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	configure := dockerd.TLSConfigure{
+		CACert:     []byte("ca"),
+		Cert:       []byte("cert"),
+		Key:        []byte("renewed key"),
+		InitSystem: facts.Systemd,
+		Privilege:  "sudo ",
+	}
+
+	if err := configure.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully secured docker: %+q", err)
+	}
+	tests.Passed("Should have succcesfully secured docker")
+
+	if err := executor.Verify(); err != nil || restarted.Calls() != 1 {
+		tests.Failed("Should have restarted docker for changed certificate: %+q", err)
+	}
+	tests.Passed("Should have restarted docker for changed certificate")
+}
@@ -0,0 +1,263 @@
+// Package firewall opens ports on linux hosts through the host's firewall, describing
+// rules independently of the backend applying them, which is one of ufw, firewalld or
+// nftables as detected by the host's facts.
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrUnknownBackend = errors.New("Firewall backend is not supported")
+	ErrInvalidRule    = errors.New("Firewall rule must have a port between 1 and 65535, a tcp or udp protocol and a CIDR source")
+
+	ErrNoNftablesConfig = errors.New("Nftables configuration loaded at boot was not found, rules would not be persisted")
+)
+
+// ports opened for box and docker.
+const (
+	BoxPort       = 5060
+	DockerTLSPort = 2376
+)
+
+// Rule defines incoming traffic allowed to a port.
+type Rule struct {
+	Port  int
+	Proto string
+
+	// From sets the CIDR traffic is allowed from, if empty traffic is allowed from anywhere.
+	From string
+}
+
+// ParseRule returns the Rule for a `PORT/PROTO` string, like `2376/tcp`, allowing traffic
+// from the CIDR. The protocol defaults to tcp.
+func ParseRule(spec string, from string) (Rule, error) {
+	port, proto := spec, "tcp"
+	if index := strings.Index(spec, "/"); index != -1 {
+		port, proto = spec[:index], spec[index+1:]
+	}
+
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%s: %q", ErrInvalidRule, spec)
+	}
+
+	rule := Rule{Port: number, Proto: proto, From: from}
+	return rule, rule.Validate()
+}
+
+// Validate returns an error if the rule can not be applied.
+func (r Rule) Validate() error {
+	if r.Port < 1 || r.Port > 65535 || (r.Proto != "tcp" && r.Proto != "udp") {
+		return fmt.Errorf("%s: %s", ErrInvalidRule, r)
+	}
+
+	if r.From != "" {
+		if _, _, err := net.ParseCIDR(r.From); err != nil {
+			return fmt.Errorf("%s: %s", ErrInvalidRule, r)
+		}
+	}
+
+	return nil
+}
+
+// String returns the rule in a readable form, like `2376/tcp from 10.0.0.0/8`.
+func (r Rule) String() string {
+	from := r.From
+	if from == "" {
+		from = "anywhere"
+	}
+
+	return fmt.Sprintf("%d/%s from %s", r.Port, r.Proto, from)
+}
+
+// canonical returns the rule with it's source in canonical form, e.g `10.0.0.0/8` for
+// `10.1.2.3/8`, so rules can be compared.
+func (r Rule) canonical() Rule {
+	if _, network, err := net.ParseCIDR(r.From); err == nil {
+		r.From = network.String()
+	}
+
+	return r
+}
+
+// ipv6 returns true if the rule's source is an IPv6 CIDR.
+func (r Rule) ipv6() bool {
+	return strings.Contains(r.From, ":")
+}
+
+// Changes contains the rules added and removed to reach the desired rules.
+type Changes struct {
+	Add    []Rule
+	Remove []Rule
+}
+
+// Empty returns true if there are no changes.
+func (c Changes) Empty() bool {
+	return len(c.Add) == 0 && len(c.Remove) == 0
+}
+
+// WriteTo writes the changes in a diff like format, a line per rule prefixed with `+`
+// for added and `-` for removed rules.
+func (c Changes) WriteTo(w io.Writer) (int64, error) {
+	var diff bytes.Buffer
+	for _, rule := range c.Remove {
+		fmt.Fprintf(&diff, "- allow %s\n", rule)
+	}
+
+	for _, rule := range c.Add {
+		fmt.Fprintf(&diff, "+ allow %s\n", rule)
+	}
+
+	return diff.WriteTo(w)
+}
+
+// Diff returns the changes needed for the current rules to contain the allowed rules
+// and none of the removed rules. Rules in neither list are left alone, so rules added
+// by others, like one allowing ssh, are never removed.
+func Diff(current []Rule, allow []Rule, remove []Rule) Changes {
+	present := map[Rule]bool{}
+	for _, rule := range current {
+		present[rule.canonical()] = true
+	}
+
+	var changes Changes
+	for _, rule := range remove {
+		rule = rule.canonical()
+		if present[rule] {
+			changes.Remove = append(changes.Remove, rule)
+			delete(present, rule)
+		}
+	}
+
+	for _, rule := range allow {
+		rule = rule.canonical()
+		if !present[rule] {
+			changes.Add = append(changes.Add, rule)
+			present[rule] = true
+		}
+	}
+
+	return changes
+}
+
+//===============================================================================================================
+
+// Backend defines a firewall which rules are applied with.
+type Backend interface {
+	// Name returns the name of the backend, as reported by facts.
+	Name() string
+
+	// Rules returns the rules currently allowing traffic to ports.
+	Rules(context.CancelContext) ([]Rule, error)
+
+	Allow(context.CancelContext, Rule) error
+	Remove(context.CancelContext, Rule) error
+
+	// Commit persists the applied rules, for backends which need it.
+	Commit(context.CancelContext) error
+}
+
+// New returns the Backend for the firewall, which is one of facts.UFW, facts.Firewalld
+// or facts.Nftables. Commands are prefixed with privilege, e.g `sudo `.
+func New(firewall string, privilege string, do exec.CommanderOption) (Backend, error) {
+	switch firewall {
+	case facts.UFW:
+		return UFW{Privilege: privilege, DoWithCmd: do}, nil
+	case facts.Firewalld:
+		return Firewalld{Privilege: privilege, DoWithCmd: do}, nil
+	case facts.Nftables:
+		return Nftables{Privilege: privilege, DoWithCmd: do}, nil
+	}
+
+	return nil, fmt.Errorf("%s: %q", ErrUnknownBackend, firewall)
+}
+
+// output executes the command through the default executor returning it's output.
+func output(ctx context.CancelContext, command string) (string, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(command), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return outs.String(), nil
+}
+
+//===============================================================================================================
+
+// Firewall implements the ops.Op interface, applying the rules with the host's firewall.
+// The changes are reported before they are applied.
+type Firewall struct {
+	// Backend sets the firewall of the host, like facts.UFW.
+	Backend string
+
+	Allow  []Rule
+	Remove []Rule
+
+	// Report sets the writer the changes are written to before they are applied.
+	Report io.Writer
+
+	// DryRun sets the changes to only be reported.
+	DryRun bool
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for applying the rules.
+func (fw Firewall) Exec(ctx context.CancelContext) error {
+	for _, rule := range append(append([]Rule(nil), fw.Allow...), fw.Remove...) {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	backend, err := New(fw.Backend, fw.Privilege, fw.DoWithCmd)
+	if err != nil {
+		return err
+	}
+
+	current, err := backend.Rules(ctx)
+	if err != nil {
+		return err
+	}
+
+	changes := Diff(current, fw.Allow, fw.Remove)
+
+	if fw.Report != nil {
+		if _, err := changes.WriteTo(fw.Report); err != nil {
+			return err
+		}
+	}
+
+	if fw.DryRun || changes.Empty() {
+		return nil
+	}
+
+	for _, rule := range changes.Remove {
+		if err := backend.Remove(ctx, rule); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range changes.Add {
+		if err := backend.Allow(ctx, rule); err != nil {
+			return err
+		}
+	}
+
+	return backend.Commit(ctx)
+}
@@ -0,0 +1,190 @@
+package firewall_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/firewall"
+	"github.com/influx6/faux/tests"
+)
+
+const ufwAdded = `Added user rules (see 'ufw status' for running firewall):
+ufw allow 22
+ufw allow 5060/tcp comment 'box'
+ufw allow from 10.0.0.0/8 to any port 2376 proto tcp
+ufw allow from 192.168.1.5 to any port 8080 proto udp
+ufw allow OpenSSH
+ufw allow 8000:8100/tcp
+ufw allow in on eth0 to any port 80 proto tcp
+ufw allow from 10.0.0.0/8 port 53 to any port 5353 proto udp
+ufw allow out 443/tcp
+ufw deny 23/tcp
+`
+
+func TestParse(t *testing.T) {
+	rules := firewall.ParseUFW(ufwAdded)
+	expected := []firewall.Rule{
+		{Port: 22, Proto: "tcp"},
+		{Port: 22, Proto: "udp"},
+		{Port: 5060, Proto: "tcp"},
+		{Port: 2376, Proto: "tcp", From: "10.0.0.0/8"},
+		{Port: 8080, Proto: "udp", From: "192.168.1.5/32"},
+	}
+
+	if !equal(rules, expected) {
+		tests.Failed("Should have parsed ufw rules: %+v", rules)
+	}
+	tests.Passed("Should have parsed ufw rules")
+
+	rules = firewall.ParseFirewalld("5060/tcp 8000-8100/tcp\n", `rule family="ipv4" source address="10.0.0.0/8" port port="2376" protocol="tcp" accept
+rule family="ipv4" source address="192.168.1.5" service name="ssh" accept
+`)
+	expected = []firewall.Rule{
+		{Port: 5060, Proto: "tcp"},
+		{Port: 2376, Proto: "tcp", From: "10.0.0.0/8"},
+	}
+
+	if !equal(rules, expected) {
+		tests.Failed("Should have parsed firewalld rules: %+v", rules)
+	}
+	tests.Passed("Should have parsed firewalld rules")
+
+	rules, handles := firewall.ParseNftables(`table inet filter {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		ip saddr 10.0.0.0/8 tcp dport 2376 accept comment "box" # handle 7
+		tcp dport 22 accept # handle 4
+		ct state established,related accept # handle 3
+	}
+}
+`)
+	expected = []firewall.Rule{
+		{Port: 2376, Proto: "tcp", From: "10.0.0.0/8"},
+		{Port: 22, Proto: "tcp"},
+	}
+
+	if !equal(rules, expected) || len(handles) != 2 || handles[0] != 7 || handles[1] != 4 {
+		tests.Failed("Should have parsed nftables rules: %+v %v", rules, handles)
+	}
+	tests.Passed("Should have parsed nftables rules")
+}
+
+func TestFirewall(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("sudo ufw show added").Stdout(ufwAdded).Times(2)
+	executor.Expect("sudo ufw delete allow 5060/tcp")
+	allow := executor.Expect("sudo ufw allow proto tcp from 192.168.0.0/16 to any port 2376")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	var report bytes.Buffer
+	fw := firewall.Firewall{
+		Backend: facts.UFW,
+		Allow: []firewall.Rule{
+			{Port: firewall.DockerTLSPort, Proto: "tcp", From: "10.0.0.0/8"},
+			{Port: firewall.DockerTLSPort, Proto: "tcp", From: "192.168.3.1/16"},
+		},
+		Remove:    []firewall.Rule{{Port: firewall.BoxPort, Proto: "tcp"}},
+		Report:    &report,
+		DryRun:    true,
+		Privilege: "sudo ",
+	}
+
+	if err := fw.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully reported changes: %+q", err)
+	}
+
+	expected := "- allow 5060/tcp from anywhere\n+ allow 2376/tcp from 192.168.0.0/16\n"
+	if report.String() != expected || allow.Calls() != 0 {
+		tests.Failed("Should have only reported changes: %q", report.String())
+	}
+	tests.Passed("Should have only reported changes")
+
+	fw.DryRun = false
+	fw.Report = nil
+
+	if err := fw.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully applied changes: %+q", err)
+	}
+	tests.Passed("Should have succcesfully applied changes")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+}
+
+func TestNftablesCommit(t *testing.T) {
+	chain := `table inet filter {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		ip saddr 10.0.0.0/8 tcp dport 2376 accept comment "box" # handle 7
+		tcp dport 22 accept # handle 4
+	}
+}
+`
+
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo nft -a list chain inet filter input").Stdout(chain)
+	executor.Expect(`sudo nft insert rule inet filter input tcp dport 5060 accept comment \"box\"`)
+	executor.Expect("sudo test -e /etc/nftables.conf").Exit(1)
+	executor.Expect("sudo test -e /etc/sysconfig/nftables.conf")
+	executor.Expect("sudo nft -a list chain inet filter input").Stdout(strings.Replace(chain, "\t\tip saddr", "\t\ttcp dport 5060 accept comment \"box\" # handle 8\n\t\tip saddr", 1))
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/nftables\.d && cat > /etc/nftables\.d/box\.nft\.box-tmp `)
+	executor.ExpectRegexp(`^sudo sh -c 'grep -qxF .*/etc/sysconfig/nftables\.conf.* \| nft -c -f /dev/stdin && `)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	fw := firewall.Firewall{
+		Backend:   facts.Nftables,
+		Allow:     []firewall.Rule{{Port: firewall.BoxPort, Proto: "tcp"}},
+		Privilege: "sudo ",
+	}
+
+	if err := fw.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully applied changes: %+q", err)
+	}
+	tests.Passed("Should have succcesfully applied changes")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	persisted := "# Managed by box, changes will be overwritten.\n" +
+		"insert rule inet filter input tcp dport 5060 accept comment \"box\"\n" +
+		"insert rule inet filter input ip saddr 10.0.0.0/8 tcp dport 2376 accept comment \"box\"\n"
+
+	if calls := executor.Calls(); string(calls[5].Stdin) != persisted {
+		tests.Failed("Should have persisted rules added by box: %q", calls[5].Stdin)
+	}
+	tests.Passed("Should have persisted rules added by box")
+}
+
+func equal(rules []firewall.Rule, expected []firewall.Rule) bool {
+	if len(rules) != len(expected) {
+		return false
+	}
+
+	for index, rule := range rules {
+		if rule != expected[index] {
+			return false
+		}
+	}
+
+	return true
+}
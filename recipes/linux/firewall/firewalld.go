@@ -0,0 +1,133 @@
+package firewall
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// Firewalld implements the Backend interface for firewalld, adding rules to the permanent
+// configuration of the default zone. Rules from anywhere are added as ports, while rules
+// from a source are added as rich rules.
+type Firewalld struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name implements the Backend interface.
+func (f Firewalld) Name() string {
+	return facts.Firewalld
+}
+
+// Rules implements the Backend interface.
+func (f Firewalld) Rules(ctx context.CancelContext) ([]Rule, error) {
+	ports, err := output(ctx, f.Privilege+"firewall-cmd --permanent --list-ports")
+	if err != nil {
+		return nil, err
+	}
+
+	richRules, err := output(ctx, f.Privilege+"firewall-cmd --permanent --list-rich-rules")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseFirewalld(ports, richRules), nil
+}
+
+// Allow implements the Backend interface.
+func (f Firewalld) Allow(ctx context.CancelContext, rule Rule) error {
+	return exec.Run(ctx, fmt.Sprintf("%sfirewall-cmd --permanent %s", f.Privilege, f.args("add", rule)), f.DoWithCmd)
+}
+
+// Remove implements the Backend interface.
+func (f Firewalld) Remove(ctx context.CancelContext, rule Rule) error {
+	return exec.Run(ctx, fmt.Sprintf("%sfirewall-cmd --permanent %s", f.Privilege, f.args("remove", rule)), f.DoWithCmd)
+}
+
+// Commit implements the Backend interface, reloading firewalld to apply the permanent
+// configuration.
+func (f Firewalld) Commit(ctx context.CancelContext) error {
+	return exec.Run(ctx, f.Privilege+"firewall-cmd --reload", f.DoWithCmd)
+}
+
+func (f Firewalld) args(action string, rule Rule) string {
+	if rule.From == "" {
+		return fmt.Sprintf("--%s-port=%d/%s", action, rule.Port, rule.Proto)
+	}
+
+	return fmt.Sprintf("--%s-rich-rule='%s'", action, RichRule(rule))
+}
+
+// RichRule returns the firewalld rich rule for the rule.
+func RichRule(rule Rule) string {
+	family := "ipv4"
+	if rule.ipv6() {
+		family = "ipv6"
+	}
+
+	return fmt.Sprintf(`rule family="%s" source address="%s" port port="%d" protocol="%s" accept`, family, rule.From, rule.Port, rule.Proto)
+}
+
+// ParseFirewalld returns the rules from the output of `firewall-cmd --list-ports` and
+// `firewall-cmd --list-rich-rules`. Port ranges and rich rules other than those accepting
+// a port from a source are ignored.
+func ParseFirewalld(ports string, richRules string) []Rule {
+	var rules []Rule
+
+	for _, port := range strings.Fields(ports) {
+		index := strings.Index(port, "/")
+		if index == -1 {
+			continue
+		}
+
+		number, err := strconv.Atoi(port[:index])
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, Rule{Port: number, Proto: port[index+1:]})
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(richRules))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasSuffix(strings.TrimSpace(line), " accept") {
+			continue
+		}
+
+		source, port, proto := attr(line, "source address"), attr(line, "port port"), attr(line, "protocol")
+
+		number, err := strconv.Atoi(port)
+		if err != nil || source == "" || proto == "" {
+			continue
+		}
+
+		if !strings.Contains(source, "/") {
+			source = singleHost(source)
+		}
+
+		rules = append(rules, Rule{Port: number, Proto: proto, From: source})
+	}
+
+	return rules
+}
+
+// attr returns the quoted value of the attribute within the rich rule.
+func attr(rule string, name string) string {
+	start := strings.Index(rule, name+`="`)
+	if start == -1 {
+		return ""
+	}
+
+	value := rule[start+len(name)+2:]
+	if end := strings.Index(value, `"`); end != -1 {
+		return value[:end]
+	}
+
+	return ""
+}
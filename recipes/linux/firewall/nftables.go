@@ -0,0 +1,226 @@
+package firewall
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// DefaultChain defines the nftables chain rules are inserted into, which is the input
+// chain of the default configuration shipped by distros.
+const DefaultChain = "inet filter input"
+
+// RulesFile defines the file the rules added by box are persisted into, which is included
+// from the host's nftables configuration.
+const RulesFile = "/etc/nftables.d/box.nft"
+
+// nftablesConfigs lists the configuration loaded at boot by the nftables service on debian
+// and arch based systems, rhel based systems and alpine.
+var nftablesConfigs = []string{"/etc/nftables.conf", "/etc/sysconfig/nftables.conf", "/etc/nftables.nft"}
+
+// Nftables implements the Backend interface for nftables, inserting rules at the start of
+// the chain so they come before any rule dropping traffic. Rules are applied to the running
+// ruleset and persisted by Commit into RulesFile, as the host's configuration can not be
+// regenerated without losing it's includes and the rules docker adds.
+type Nftables struct {
+	// Chain sets the family, table and name of the chain, it defaults to DefaultChain.
+	Chain string
+
+	// Config sets the nftables configuration loaded at boot which includes RulesFile, it
+	// defaults to the first of nftablesConfigs found on the host.
+	Config string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name implements the Backend interface.
+func (n Nftables) Name() string {
+	return facts.Nftables
+}
+
+// Rules implements the Backend interface.
+func (n Nftables) Rules(ctx context.CancelContext) ([]Rule, error) {
+	rules, _, err := n.list(ctx)
+	return rules, err
+}
+
+// Allow implements the Backend interface. Rules are commented with `box`, marking those
+// persisted by Commit.
+func (n Nftables) Allow(ctx context.CancelContext, rule Rule) error {
+	return exec.Run(ctx, fmt.Sprintf(`%snft insert rule %s %s comment \"box\"`, n.Privilege, n.chain(), statement(rule)), n.DoWithCmd)
+}
+
+// Remove implements the Backend interface, deleting every rule of the chain matching the rule.
+func (n Nftables) Remove(ctx context.CancelContext, rule Rule) error {
+	rules, handles, err := n.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	for index, current := range rules {
+		if current.canonical() != rule.canonical() {
+			continue
+		}
+
+		if err := exec.Run(ctx, fmt.Sprintf("%snft delete rule %s handle %d", n.Privilege, n.chain(), handles[index]), n.DoWithCmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Commit implements the Backend interface, writing the rules of the chain added by box
+// into RulesFile and including it from the host's configuration, so they're loaded again
+// at boot. The include is only added once the configuration is checked to load with it.
+func (n Nftables) Commit(ctx context.CancelContext) error {
+	config, err := n.config(ctx)
+	if err != nil {
+		return err
+	}
+
+	chain, err := output(ctx, fmt.Sprintf("%snft -a list chain %s", n.Privilege, n.chain()))
+	if err != nil {
+		return err
+	}
+
+	var rules bytes.Buffer
+	rules.WriteString("# Managed by box, changes will be overwritten.\n")
+
+	for _, rule := range boxRules(chain) {
+		fmt.Fprintf(&rules, "insert rule %s %s comment \"box\"\n", n.chain(), statement(rule))
+	}
+
+	if _, err := exec.UpdateFile(ctx, RulesFile, rules.Bytes(), 0644, n.Privilege); err != nil {
+		return err
+	}
+
+	include := fmt.Sprintf("include %q", RulesFile)
+	check := fmt.Sprintf("printf '%%s\\n' %s %s | nft -c -f /dev/stdin", exec.QuotePath(fmt.Sprintf("include %q", config)), exec.QuotePath(include))
+	script := fmt.Sprintf("grep -qxF %s %s || { %s && printf '\\n%%s\\n' %s >> %s; }", exec.QuotePath(include), exec.QuotePath(config), check, exec.QuotePath(include), exec.QuotePath(config))
+
+	return exec.Run(ctx, n.Privilege+"sh -c "+exec.QuotePath(script), n.DoWithCmd)
+}
+
+func (n Nftables) chain() string {
+	if n.Chain == "" {
+		return DefaultChain
+	}
+
+	return n.Chain
+}
+
+// config returns the nftables configuration loaded at boot.
+func (n Nftables) config(ctx context.CancelContext) (string, error) {
+	if n.Config != "" {
+		return n.Config, nil
+	}
+
+	for _, config := range nftablesConfigs {
+		exists, err := exec.FileExists(ctx, config, n.Privilege)
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			return config, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: %q", ErrNoNftablesConfig, nftablesConfigs)
+}
+
+// list returns the rules of the chain along with their handles.
+func (n Nftables) list(ctx context.CancelContext) ([]Rule, []int, error) {
+	chain, err := output(ctx, fmt.Sprintf("%snft -a list chain %s", n.Privilege, n.chain()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rules, handles := ParseNftables(chain)
+	return rules, handles, nil
+}
+
+// statement returns the nftables statement accepting traffic for the rule.
+func statement(rule Rule) string {
+	var match string
+	if rule.From != "" {
+		family := "ip"
+		if rule.ipv6() {
+			family = "ip6"
+		}
+
+		match = fmt.Sprintf("%s saddr %s ", family, rule.From)
+	}
+
+	return fmt.Sprintf("%s%s dport %d accept", match, rule.Proto, rule.Port)
+}
+
+// boxRules returns the rules commented with `box` from the output of `nft -a list chain`.
+func boxRules(chain string) []Rule {
+	var lines []string
+	for _, line := range strings.Split(chain, "\n") {
+		if strings.Contains(line, `comment "box"`) {
+			lines = append(lines, line)
+		}
+	}
+
+	rules, _ := ParseNftables(strings.Join(lines, "\n"))
+	return rules
+}
+
+// ParseNftables returns the rules accepting traffic to a single port from the output of
+// `nft -a list chain`, along with their handles.
+func ParseNftables(chain string) ([]Rule, []int) {
+	var rules []Rule
+	var handles []int
+
+	scanner := bufio.NewScanner(strings.NewReader(chain))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		var rule Rule
+		handle, accept := -1, false
+
+		for index := 0; index < len(fields); index++ {
+			field, next := fields[index], ""
+			if index+1 < len(fields) {
+				next = fields[index+1]
+			}
+
+			switch {
+			case (field == "ip" || field == "ip6") && next == "saddr" && index+2 < len(fields):
+				rule.From = fields[index+2]
+				if !strings.Contains(rule.From, "/") {
+					rule.From = singleHost(rule.From)
+				}
+				index += 2
+			case (field == "tcp" || field == "udp") && next == "dport" && index+2 < len(fields):
+				rule.Proto = field
+				rule.Port, _ = strconv.Atoi(fields[index+2])
+				index += 2
+			case field == "accept":
+				accept = true
+			case field == "handle" && next != "":
+				handle, _ = strconv.Atoi(next)
+				index++
+			}
+		}
+
+		if !accept || rule.Port == 0 || handle == -1 {
+			continue
+		}
+
+		rules = append(rules, rule)
+		handles = append(handles, handle)
+	}
+
+	return rules, handles
+}
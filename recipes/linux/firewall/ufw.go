@@ -0,0 +1,153 @@
+package firewall
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// UFW implements the Backend interface for ufw. Rules are added whether ufw is active or
+// not, it's never enabled as that could lock out the ssh connection box is using.
+type UFW struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name implements the Backend interface.
+func (u UFW) Name() string {
+	return facts.UFW
+}
+
+// Rules implements the Backend interface, parsing the output of `ufw show added` which
+// lists the rules whether ufw is active or not, unlike `ufw status`.
+func (u UFW) Rules(ctx context.CancelContext) ([]Rule, error) {
+	added, err := output(ctx, u.Privilege+"ufw show added")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseUFW(added), nil
+}
+
+// Allow implements the Backend interface.
+func (u UFW) Allow(ctx context.CancelContext, rule Rule) error {
+	return exec.Run(ctx, fmt.Sprintf("%sufw allow %s", u.Privilege, u.args(rule)), u.DoWithCmd)
+}
+
+// Remove implements the Backend interface.
+func (u UFW) Remove(ctx context.CancelContext, rule Rule) error {
+	return exec.Run(ctx, fmt.Sprintf("%sufw delete allow %s", u.Privilege, u.args(rule)), u.DoWithCmd)
+}
+
+// Commit implements the Backend interface, ufw persists rules as they're added.
+func (u UFW) Commit(ctx context.CancelContext) error {
+	return nil
+}
+
+func (u UFW) args(rule Rule) string {
+	if rule.From == "" {
+		return fmt.Sprintf("%d/%s", rule.Port, rule.Proto)
+	}
+
+	return fmt.Sprintf("proto %s from %s to any port %d", rule.Proto, rule.From, rule.Port)
+}
+
+// ParseUFW returns the allow rules listed in the output of `ufw show added`, like
+// `ufw allow 22/tcp` or `ufw allow from 10.0.0.0/8 to any port 2376 proto tcp`. Rules for
+// application profiles, port ranges, interfaces, outgoing traffic, source ports or
+// specific destinations are ignored.
+func ParseUFW(added string) []Rule {
+	var rules []Rule
+
+	scanner := bufio.NewScanner(strings.NewReader(added))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "ufw" || fields[1] != "allow" {
+			continue
+		}
+
+		var port, proto, from, last string
+		supported := true
+
+	parse:
+		for index := 2; index < len(fields) && supported; index++ {
+			field, next := fields[index], ""
+			if index+1 < len(fields) {
+				next = fields[index+1]
+			}
+
+			switch field {
+			case "in":
+			case "comment":
+				break parse
+			case "from", "to":
+				if field == "to" && next != "any" {
+					supported = false
+				}
+
+				if field == "from" && next != "any" {
+					from = next
+				}
+
+				last = field
+				index++
+			case "port":
+				if last != "to" {
+					supported = false
+				}
+
+				port = next
+				index++
+			case "proto":
+				proto = next
+				index++
+			default:
+				if index != 2 {
+					supported = false
+					break
+				}
+
+				port = field
+				if slash := strings.Index(field, "/"); slash != -1 {
+					port, proto = field[:slash], field[slash+1:]
+				}
+			}
+		}
+
+		number, err := strconv.Atoi(port)
+		if !supported || err != nil || (proto != "" && proto != "tcp" && proto != "udp") {
+			continue
+		}
+
+		if from != "" && !strings.Contains(from, "/") {
+			from = singleHost(from)
+		}
+
+		// Rules without a protocol allow both.
+		protos := []string{proto}
+		if proto == "" {
+			protos = []string{"tcp", "udp"}
+		}
+
+		for _, proto := range protos {
+			rules = append(rules, Rule{Port: number, Proto: proto, From: from})
+		}
+	}
+
+	return rules
+}
+
+// singleHost returns the address as a CIDR matching only itself, which is how ufw and
+// firewalld list sources given as such.
+func singleHost(addr string) string {
+	if strings.Contains(addr, ":") {
+		return addr + "/128"
+	}
+
+	return addr + "/32"
+}
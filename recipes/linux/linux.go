@@ -2,12 +2,17 @@ package linux
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/influx6/box"
-	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/exec/facts"
 	"github.com/influx6/faux/context"
 	"github.com/influx6/faux/ops"
 
+	_ "github.com/influx6/box/recipes/linux/alpine"
+	_ "github.com/influx6/box/recipes/linux/arch"
+	_ "github.com/influx6/box/recipes/linux/debian"
+	_ "github.com/influx6/box/recipes/linux/rhel"
 	_ "github.com/influx6/box/recipes/linux/ubuntu"
 )
 
@@ -21,18 +26,34 @@ var (
 // box on a system.
 type LinuxProvisioner struct {
 	OSName string
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	DockerVersion string `json:"docker_version"`
 }
 
 // Exec implements the box.Spell system.
 func (dw *LinuxProvisioner) Exec(ctx context.CancelContext) error {
-	info, err := osinfo.OSInfo(ctx)
+	hostFacts, err := facts.Collect(ctx)
 	if err != nil {
 		return err
 	}
 
-	provisioner, err := box.CreateWithJSON(fmt.Sprintf("linux/%s", info.ID), map[string]interface{}{
-		"os_info": info,
-	})
+	info := hostFacts.OS
+
+	config := map[string]interface{}{
+		"os_info":        info,
+		"facts":          hostFacts,
+		"docker_version": dw.DockerVersion,
+	}
+
+	// Attempt the distro's own provisioner, then those of the distros it's derived from.
+	var provisioner ops.Op
+	for _, id := range append([]string{info.ID}, strings.Fields(info.IDLike)...) {
+		if provisioner, err = box.CreateWithJSON(fmt.Sprintf("linux/%s", id), config); err == nil {
+			break
+		}
+	}
+
 	if err != nil {
 		return fmt.Errorf("Linux Distro %q not supported: %+q", info.ID, err)
 	}
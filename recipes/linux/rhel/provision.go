@@ -0,0 +1,121 @@
+package rhel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+)
+
+// package managers
+const (
+	DNF = "dnf"
+	Yum = "yum"
+)
+
+// Packages contains the packages installed on rhel based systems.
+var Packages = packages.Names("git", "curl", "wget", "openssh-server")
+
+// DockerPackages contains the packages installed from docker's repository.
+var DockerPackages = packages.Names("docker-ce", "docker-ce-cli", "containerd.io")
+
+// cliRelease defines the first docker release which split the cli into docker-ce-cli.
+const cliRelease = "18.09"
+
+// ContainerSELinux contains the SELinux policies docker's packages require when SELinux is on.
+var ContainerSELinux = packages.Package{Name: "container-selinux"}
+
+// custom executors.
+var (
+	DockerServiceEnable = exec.New(exec.Command("sudo systemctl enable docker.service"))
+	DockerServiceStart  = exec.New(exec.Command("sudo systemctl start docker.service"))
+)
+
+// SudoInstaller returns a Commander which installs sudo with the giving package manager.
+func SudoInstaller(manager string) *exec.Commander {
+	return exec.New(exec.Command(fmt.Sprintf("if ! type sudo; then %s install -y sudo; fi", manager)))
+}
+
+// PluginsPackage returns the package providing the config-manager command of the giving
+// package manager.
+func PluginsPackage(manager string) packages.Package {
+	if manager == Yum {
+		return packages.Package{Name: "yum-utils"}
+	}
+
+	return packages.Package{Name: "dnf-plugins-core"}
+}
+
+// PinnedDockerPackages returns DockerPackages with docker-ce and docker-ce-cli pinned to
+// the highest version in docker's repository matching the constraint.
+func PinnedDockerPackages(ctx context.CancelContext, manager string, constraint dockerapt.Constraint) ([]packages.Package, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(fmt.Sprintf("%s list --showduplicates docker-ce", manager)), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	version, err := dockerapt.SelectVersion(ListVersions(outs.Bytes(), "docker-ce"), constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := []packages.Package{{Name: "docker-ce", Version: version}}
+	if dockerapt.CompareVersions(dockerapt.UpstreamVersion(version), cliRelease) >= 0 {
+		pkgs = append(pkgs, packages.Package{Name: "docker-ce-cli", Version: version}, packages.Package{Name: "containerd.io"})
+	}
+
+	return pkgs, nil
+}
+
+// ListVersions returns the versions of the package listed by `dnf list --showduplicates`,
+// whose lines have the form `docker-ce.x86_64  3:24.0.7-1.el8  docker-ce-stable`.
+func ListVersions(list []byte, name string) []string {
+	var versions []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], name+".") {
+			continue
+		}
+
+		versions = append(versions, fields[1])
+	}
+
+	return versions
+}
+
+//===============================================================================================================
+
+// DockerRepository will run necessary commands to add the docker-ce repository for a rhel system.
+type DockerRepository struct {
+	Manager   string
+	Distro    string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for adding the docker-ce repository.
+func (repo DockerRepository) Exec(ctx context.CancelContext) error {
+	url := fmt.Sprintf("https://download.docker.com/linux/%s/docker-ce.repo", repo.Distro)
+
+	// dnf5 replaced the --add-repo flag with the addrepo subcommand.
+	command := fmt.Sprintf("sudo dnf config-manager --add-repo %s || sudo dnf config-manager addrepo --from-repofile=%s", url, url)
+	if repo.Manager == Yum {
+		command = fmt.Sprintf("sudo yum-config-manager --add-repo %s", url)
+	}
+
+	cmd := exec.New(exec.Command(command), exec.Async())
+
+	if repo.DoWithCmd != nil {
+		repo.DoWithCmd(cmd)
+	}
+
+	return cmd.Exec(ctx)
+}
@@ -0,0 +1,136 @@
+package rhel
+
+import (
+	"strings"
+
+	"github.com/influx6/box"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/context"
+	"github.com/influx6/faux/ops"
+)
+
+var (
+	_ = box.RegisterJSON("linux/fedora", func() ops.Op {
+		return &rhelProvisioner{}
+	})
+
+	_ = box.RegisterJSON("linux/centos", func() ops.Op {
+		return &rhelProvisioner{}
+	})
+
+	_ = box.RegisterJSON("linux/rhel", func() ops.Op {
+		return &rhelProvisioner{}
+	})
+
+	_ = box.RegisterJSON("linux/rocky", func() ops.Op {
+		return &rhelProvisioner{}
+	})
+)
+
+// rhelProvisioner implements ops.Op interface and contains necessary procedures to provision a
+// fedora, centos, rhel or rocky linux vm/system for app deployment with box.
+type rhelProvisioner struct {
+	Info  osinfo.Info `json:"os_info"`
+	Facts facts.Facts `json:"facts"`
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	DockerVersion string `json:"docker_version"`
+}
+
+func (rhp *rhelProvisioner) Exec(ctx context.CancelContext) error {
+	constraint, err := dockerapt.ParseConstraint(rhp.DockerVersion)
+	if err != nil {
+		return err
+	}
+
+	manager := PackageManager(rhp.Info, rhp.Facts)
+	pm := packages.DNF{Binary: manager, Privilege: "sudo "}
+
+	// Attempt to install sudo
+	if err := SudoInstaller(manager).Exec(ctx); err != nil {
+		return err
+	}
+
+	// Update package metadata
+	if err := pm.Update(ctx); err != nil {
+		return err
+	}
+
+	// Attempt to install necessary packages packages, along with the SELinux policies for
+	// containers which docker's packages require when SELinux is on.
+	pkgs := Packages
+	if rhp.Facts.SELinux == facts.SELinuxEnforcing || rhp.Facts.SELinux == facts.SELinuxPermissive {
+		pkgs = append(append([]packages.Package{}, Packages...), ContainerSELinux)
+	}
+
+	if err := pm.Install(ctx, pkgs...); err != nil {
+		return err
+	}
+
+	// Docker is already installed, skip adding it's repository.
+	if !rhp.Facts.DockerInstalled || !dockerapt.Satisfies(rhp.Facts.DockerVersion, rhp.DockerVersion) {
+		if err := pm.Install(ctx, PluginsPackage(manager)); err != nil {
+			return err
+		}
+
+		if err := (DockerRepository{Manager: manager, Distro: Distro(rhp.Info)}).Exec(ctx); err != nil {
+			return err
+		}
+
+		pkgs := DockerPackages
+		if len(constraint) != 0 {
+			if pkgs, err = PinnedDockerPackages(ctx, manager, constraint); err != nil {
+				return err
+			}
+		}
+
+		if err := pm.Install(ctx, pkgs...); err != nil {
+			return err
+		}
+	}
+
+	// Enable and start docker through systemd.
+	if err := DockerServiceEnable.Exec(ctx); err != nil {
+		return err
+	}
+
+	if err := DockerServiceStart.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PackageManager returns the package manager of the host, using the one found in the
+// host facts, else dnf for fedora and releases from 8 onwards, or yum for older ones.
+func PackageManager(info osinfo.Info, hostFacts facts.Facts) string {
+	switch hostFacts.PackageManager {
+	case DNF, Yum:
+		return hostFacts.PackageManager
+	}
+
+	if info.ID == "fedora" {
+		return DNF
+	}
+
+	switch strings.SplitN(info.VersionID, ".", 2)[0] {
+	case "5", "6", "7":
+		return Yum
+	}
+
+	return DNF
+}
+
+// Distro returns the name of the distribution within docker's repository for the giving
+// os info. Rocky linux uses the centos repository.
+func Distro(info osinfo.Info) string {
+	switch info.ID {
+	case "fedora", "rhel":
+		return info.ID
+	}
+
+	return "centos"
+}
@@ -0,0 +1,112 @@
+package rhel
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/faux/tests"
+)
+
+func TestRockyProvisioner(t *testing.T) {
+	expected := []string{
+		"if ! type sudo; then dnf install -y sudo; fi",
+		"sudo dnf -y makecache",
+		"sudo dnf install -y git curl wget openssh-server container-selinux",
+		"sudo dnf install -y dnf-plugins-core",
+		"sudo dnf config-manager --add-repo https://download.docker.com/linux/centos/docker-ce.repo || sudo dnf config-manager addrepo --from-repofile=https://download.docker.com/linux/centos/docker-ce.repo",
+		"sudo dnf install -y docker-ce docker-ce-cli containerd.io",
+		"sudo systemctl enable docker.service",
+		"sudo systemctl start docker.service",
+	}
+
+	provisioner := &rhelProvisioner{
+		Info:  osinfo.Info{ID: "rocky", VersionID: "8.6"},
+		Facts: facts.Facts{PackageManager: DNF, SELinux: facts.SELinuxEnforcing},
+	}
+
+	if err := provision(provisioner, expected, nil); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestCentOSProvisionerUsesYum(t *testing.T) {
+	expected := []string{
+		"if ! type sudo; then yum install -y sudo; fi",
+		"sudo yum -y makecache",
+		"sudo yum install -y git curl wget openssh-server",
+		"sudo yum install -y yum-utils",
+		"sudo yum-config-manager --add-repo https://download.docker.com/linux/centos/docker-ce.repo",
+		"sudo yum install -y docker-ce docker-ce-cli containerd.io",
+		"sudo systemctl enable docker.service",
+		"sudo systemctl start docker.service",
+	}
+
+	provisioner := &rhelProvisioner{
+		Info:  osinfo.Info{ID: "centos", VersionID: "7"},
+		Facts: facts.Facts{SELinux: facts.SELinuxDisabled},
+	}
+
+	if err := provision(provisioner, expected, nil); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestFedoraProvisionerDockerVersion(t *testing.T) {
+	expected := []string{
+		"if ! type sudo; then dnf install -y sudo; fi",
+		"sudo dnf -y makecache",
+		"sudo dnf install -y git curl wget openssh-server",
+		"sudo dnf install -y dnf-plugins-core",
+		"sudo dnf config-manager --add-repo https://download.docker.com/linux/fedora/docker-ce.repo || sudo dnf config-manager addrepo --from-repofile=https://download.docker.com/linux/fedora/docker-ce.repo",
+		"dnf list --showduplicates docker-ce",
+		"sudo dnf install -y docker-ce-3:24.0.9-1.fc39 docker-ce-cli-3:24.0.9-1.fc39 containerd.io",
+		"sudo systemctl enable docker.service",
+		"sudo systemctl start docker.service",
+	}
+
+	list := strings.Join([]string{
+		"Available Packages",
+		"docker-ce.x86_64    3:24.0.7-1.fc39    docker-ce-stable",
+		"docker-ce.x86_64    3:24.0.9-1.fc39    docker-ce-stable",
+		"docker-ce.x86_64    3:25.0.3-1.fc39    docker-ce-stable",
+	}, "\n")
+
+	provisioner := &rhelProvisioner{
+		Info:          osinfo.Info{ID: "fedora", VersionID: "39"},
+		Facts:         facts.Facts{DockerInstalled: true, DockerVersion: "19.03.5"},
+		DockerVersion: ">=20.10 <25",
+	}
+
+	if err := provision(provisioner, expected, map[string]string{"dnf list --showduplicates docker-ce": list}); err != nil {
+		tests.Failed("Should have installed highest docker release matching constraint: %+q", err)
+	}
+	tests.Passed("Should have installed highest docker release matching constraint")
+}
+
+func provision(provisioner *rhelProvisioner, expected []string, outputs map[string]string) error {
+	executor := exectest.New().InOrder()
+	for _, command := range expected {
+		executor.Expect(command).Stdout(outputs[command])
+	}
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := provisioner.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	return executor.Verify()
+}
@@ -0,0 +1,422 @@
+// Package sshd hardens the ssh server of linux hosts by managing a block of settings at
+// the top of it's sshd_config, refusing to apply settings which would lock out the user
+// box connects as.
+package sshd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/firewall"
+	"github.com/influx6/box/recipes/linux/users"
+	"github.com/influx6/faux/context"
+	"golang.org/x/crypto/ssh"
+)
+
+// errors
+var (
+	ErrLockout        = errors.New("Refusing to apply sshd settings which would lock out the connecting user")
+	ErrInvalidSetting = errors.New("Invalid sshd setting")
+)
+
+// ConfigPath defines the path of the ssh server's configuration.
+const ConfigPath = "/etc/ssh/sshd_config"
+
+// DefaultPort defines the port sshd listens on unless configured otherwise.
+const DefaultPort = 22
+
+// markers of the block of settings managed by box.
+const (
+	beginMarker = "# BEGIN box managed settings"
+	endMarker   = "# END box managed settings"
+	replaced    = "# Replaced by box: "
+)
+
+// values of PermitRootLogin.
+const (
+	RootLoginNo               = "no"
+	RootLoginProhibitPassword = "prohibit-password"
+	RootLoginYes              = "yes"
+)
+
+// clientCiphers lists the ciphers supported by box's ssh client, at least one of the
+// configured ciphers must be among them for box to connect.
+var clientCiphers = map[string]bool{
+	"aes128-ctr":                    true,
+	"aes192-ctr":                    true,
+	"aes256-ctr":                    true,
+	"aes128-gcm@openssh.com":        true,
+	"aes256-gcm@openssh.com":        true,
+	"chacha20-poly1305@openssh.com": true,
+}
+
+// Config contains the sshd settings managed by box, settings which are not set are left
+// as they are.
+type Config struct {
+	// Port sets the port sshd listens on, the connection box uses survives the change
+	// but the host's inventory entry must be updated for later connections. Harden opens
+	// the port on the host's firewall and labels it for SELinux before sshd is reloaded.
+	Port int
+
+	// PasswordAuthentication enables or disables logging in with passwords, disabling it
+	// requires the connecting user to have an authorized key.
+	PasswordAuthentication *bool
+
+	// PermitRootLogin sets how root may login, one of RootLoginNo,
+	// RootLoginProhibitPassword or RootLoginYes.
+	PermitRootLogin string
+
+	// AllowUsers lists the users allowed to login, which may contain patterns.
+	AllowUsers []string
+
+	Ciphers []string
+}
+
+// Settings returns the sshd_config keywords and values of the config in a stable order.
+func (c Config) Settings() [][2]string {
+	var settings [][2]string
+
+	if c.Port != 0 {
+		settings = append(settings, [2]string{"Port", strconv.Itoa(c.Port)})
+	}
+
+	if c.PasswordAuthentication != nil {
+		settings = append(settings, [2]string{"PasswordAuthentication", yesNo(*c.PasswordAuthentication)})
+
+		// KbdInteractiveAuthentication is unknown to sshd before OpenSSH 8.7, while later
+		// releases still accept it's older name ChallengeResponseAuthentication.
+		if !*c.PasswordAuthentication {
+			settings = append(settings, [2]string{"ChallengeResponseAuthentication", "no"})
+		}
+	}
+
+	if c.PermitRootLogin != "" {
+		settings = append(settings, [2]string{"PermitRootLogin", c.PermitRootLogin})
+	}
+
+	if len(c.AllowUsers) != 0 {
+		settings = append(settings, [2]string{"AllowUsers", strings.Join(c.AllowUsers, " ")})
+	}
+
+	if len(c.Ciphers) != 0 {
+		settings = append(settings, [2]string{"Ciphers", strings.Join(c.Ciphers, ",")})
+	}
+
+	return settings
+}
+
+// Validate returns an error if any of the settings is invalid.
+func (c Config) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("%s: Port %d", ErrInvalidSetting, c.Port)
+	}
+
+	switch c.PermitRootLogin {
+	case "", RootLoginNo, RootLoginProhibitPassword, RootLoginYes:
+	default:
+		return fmt.Errorf("%s: PermitRootLogin %q", ErrInvalidSetting, c.PermitRootLogin)
+	}
+
+	for _, value := range append(append([]string(nil), c.AllowUsers...), c.Ciphers...) {
+		if value == "" || strings.ContainsAny(value, " \t\n,#") {
+			return fmt.Errorf("%s: %q", ErrInvalidSetting, value)
+		}
+	}
+
+	return nil
+}
+
+// Apply returns the sshd_config with the settings written into a block at it's top,
+// replacing the previous block. sshd uses the first value it reads for most keywords,
+// so the block comes before any Include, and lines setting the same keywords outside
+// of Match blocks are commented out.
+func Apply(existing []byte, config Config) []byte {
+	settings := config.Settings()
+
+	managed := map[string]bool{}
+	for _, setting := range settings {
+		managed[strings.ToLower(setting[0])] = true
+	}
+
+	var out bytes.Buffer
+	if len(settings) != 0 {
+		out.WriteString(beginMarker + "\n")
+		for _, setting := range settings {
+			fmt.Fprintf(&out, "%s %s\n", setting[0], setting[1])
+		}
+		out.WriteString(endMarker + "\n")
+	}
+
+	inBlock, inMatch := false, false
+
+	scanner := bufio.NewScanner(bytes.NewReader(existing))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == beginMarker:
+			inBlock = true
+			continue
+		case trimmed == endMarker:
+			inBlock = false
+			continue
+		case inBlock:
+			continue
+		}
+
+		// Lines previously replaced are restored once box no longer manages them.
+		if strings.HasPrefix(line, replaced) {
+			line = strings.TrimPrefix(line, replaced)
+			trimmed = strings.TrimSpace(line)
+		}
+
+		fields := strings.Fields(trimmed)
+		if len(fields) != 0 && !strings.HasPrefix(trimmed, "#") {
+			keyword := strings.ToLower(strings.SplitN(fields[0], "=", 2)[0])
+
+			if keyword == "match" {
+				inMatch = true
+			}
+
+			if !inMatch && managed[keyword] {
+				line = replaced + line
+			}
+		}
+
+		out.WriteString(line + "\n")
+	}
+
+	return out.Bytes()
+}
+
+// yesNo returns the sshd_config value of the boolean.
+func yesNo(val bool) string {
+	if val {
+		return "yes"
+	}
+
+	return "no"
+}
+
+//===============================================================================================================
+
+// Harden implements the ops.Op interface, applying the config to the host's sshd_config,
+// validating it with `sshd -t` and reloading sshd. Before anything is changed it checks
+// the settings leave the connecting user able to login, refusing to apply them otherwise.
+type Harden struct {
+	Config Config
+
+	// User sets the user box connects as, it defaults to the user commands run as.
+	User string
+
+	// Keys lists the public keys box connects with, one of them must be authorized for
+	// the user when password authentication is disabled. If empty the user must have
+	// at least one authorized key.
+	Keys []ssh.PublicKey
+
+	// InitSystem sets the init system sshd is reloaded with, like facts.Systemd.
+	InitSystem string
+
+	// Firewall sets the firewall of the host a changed Port is opened on, like facts.UFW.
+	// If empty the host is taken to have no firewall.
+	Firewall string
+
+	// SELinux sets the SELinux mode of the host, like facts.SELinuxEnforcing, unless
+	// disabled a changed Port is labeled for sshd with semanage.
+	SELinux string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for hardening sshd.
+func (h Harden) Exec(ctx context.CancelContext) error {
+	if err := h.Config.Validate(); err != nil {
+		return err
+	}
+
+	if err := h.checkLockout(ctx); err != nil {
+		return err
+	}
+
+	existing, err := h.cat(ctx, ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	updated := Apply(existing, h.Config)
+	if bytes.Equal(existing, updated) {
+		return nil
+	}
+
+	check := ConfigPath + ".box-check"
+	if err := exec.WriteFile(ctx, check, updated, 0600, h.Privilege); err != nil {
+		return err
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%s/usr/sbin/sshd -t -f %s", h.Privilege, check), h.DoWithCmd); err != nil {
+		exec.Run(ctx, fmt.Sprintf("%srm -f %s", h.Privilege, check), h.DoWithCmd)
+		return err
+	}
+
+	// sshd fails to bind a port SELinux doesn't allow it, and a port closed by the
+	// firewall leaves later connections unable to reach it.
+	if err := h.openPort(ctx); err != nil {
+		exec.Run(ctx, fmt.Sprintf("%srm -f %s", h.Privilege, check), h.DoWithCmd)
+		return err
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%scp -p %s %s.bak && %smv -f %s %s", h.Privilege, ConfigPath, ConfigPath, h.Privilege, check, ConfigPath), h.DoWithCmd); err != nil {
+		return err
+	}
+
+	return exec.Run(ctx, ReloadCommand(h.InitSystem, h.Privilege), h.DoWithCmd)
+}
+
+// openPort labels a changed Port for sshd with SELinux and opens it on the firewall.
+func (h Harden) openPort(ctx context.CancelContext) error {
+	port := h.Config.Port
+	if port == 0 || port == DefaultPort {
+		return nil
+	}
+
+	if h.SELinux != "" && h.SELinux != facts.SELinuxDisabled {
+		// Ports already labeled with another type are modified rather than added.
+		label := fmt.Sprintf("%ssemanage port -a -t ssh_port_t -p tcp %d || %ssemanage port -m -t ssh_port_t -p tcp %d", h.Privilege, port, h.Privilege, port)
+		if err := exec.Run(ctx, label, h.DoWithCmd); err != nil {
+			return err
+		}
+	}
+
+	if h.Firewall == "" {
+		return nil
+	}
+
+	open := firewall.Firewall{
+		Backend:   h.Firewall,
+		Allow:     []firewall.Rule{{Port: port, Proto: "tcp"}},
+		Privilege: h.Privilege,
+		DoWithCmd: h.DoWithCmd,
+	}
+
+	return open.Exec(ctx)
+}
+
+// checkLockout returns ErrLockout if the connecting user would be unable to login with
+// the config.
+func (h Harden) checkLockout(ctx context.CancelContext) error {
+	user := h.User
+	if user == "" {
+		var outs bytes.Buffer
+		if err := exec.New(exec.Command("id -un"), exec.Sync(), exec.Output(&outs)).Exec(ctx); err != nil {
+			return err
+		}
+
+		user = strings.TrimSpace(outs.String())
+	}
+
+	config := h.Config
+
+	if user == "root" && config.PermitRootLogin == RootLoginNo {
+		return fmt.Errorf("%s: root login is disabled while connected as root", ErrLockout)
+	}
+
+	if len(config.AllowUsers) != 0 && !allowed(user, config.AllowUsers) {
+		return fmt.Errorf("%s: %q is not within AllowUsers", ErrLockout, user)
+	}
+
+	if len(config.Ciphers) != 0 {
+		supported := false
+		for _, cipher := range config.Ciphers {
+			supported = supported || clientCiphers[cipher]
+		}
+
+		if !supported {
+			return fmt.Errorf("%s: none of the ciphers are supported by box", ErrLockout)
+		}
+	}
+
+	if config.PasswordAuthentication == nil || *config.PasswordAuthentication {
+		return nil
+	}
+
+	home, err := users.HomeDir(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	authorized, err := h.cat(ctx, path.Join(home, ".ssh", "authorized_keys"))
+	if err != nil {
+		return fmt.Errorf("%s: unable to read authorized keys of %q: %s", ErrLockout, user, err)
+	}
+
+	keys := map[string]bool{}
+	for rest := authorized; len(rest) != 0; {
+		key, _, _, next, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			break
+		}
+
+		keys[string(key.Marshal())] = true
+		rest = next
+	}
+
+	if len(h.Keys) == 0 && len(keys) != 0 {
+		return nil
+	}
+
+	for _, key := range h.Keys {
+		if keys[string(key.Marshal())] {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: password login is disabled and %q has no authorized key box connects with", ErrLockout, user)
+}
+
+// cat returns the contents of the file, read with root privileges.
+func (h Harden) cat(ctx context.CancelContext, file string) ([]byte, error) {
+	var outs bytes.Buffer
+	catCmd := exec.New(exec.Command(fmt.Sprintf("%scat %s", h.Privilege, file)), exec.Sync(), exec.Output(&outs))
+
+	if err := catCmd.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return outs.Bytes(), nil
+}
+
+// allowed returns true if the user matches any of the AllowUsers patterns, which have
+// the form `user` or `user@host` and may contain `*` and `?` wildcards.
+func allowed(user string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.SplitN(pattern, "@", 2)[0]
+
+		if matched, err := path.Match(pattern, user); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReloadCommand returns the command reloading sshd with the init system, whose service is
+// named ssh on debian derivatives and sshd elsewhere.
+func ReloadCommand(initSystem string, privilege string) string {
+	switch initSystem {
+	case facts.Systemd:
+		return fmt.Sprintf("%ssystemctl reload ssh || %ssystemctl reload sshd", privilege, privilege)
+	case facts.OpenRC:
+		return privilege + "rc-service sshd reload"
+	}
+
+	return fmt.Sprintf("%sservice ssh reload || %sservice sshd reload", privilege, privilege)
+}
@@ -0,0 +1,189 @@
+package sshd_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/box/recipes/linux/sshd"
+	"github.com/influx6/faux/tests"
+	"golang.org/x/crypto/ssh"
+)
+
+const sshdConfig = `Include /etc/ssh/sshd_config.d/*.conf
+Port 22
+#PermitRootLogin prohibit-password
+PasswordAuthentication yes
+UsePAM yes
+
+Match User backup
+	PasswordAuthentication yes
+`
+
+func TestApply(t *testing.T) {
+	disabled := false
+	config := sshd.Config{PasswordAuthentication: &disabled, PermitRootLogin: sshd.RootLoginNo}
+
+	expected := `# BEGIN box managed settings
+PasswordAuthentication no
+ChallengeResponseAuthentication no
+PermitRootLogin no
+# END box managed settings
+Include /etc/ssh/sshd_config.d/*.conf
+Port 22
+#PermitRootLogin prohibit-password
+# Replaced by box: PasswordAuthentication yes
+UsePAM yes
+
+Match User backup
+	PasswordAuthentication yes
+`
+
+	applied := sshd.Apply([]byte(sshdConfig), config)
+	if string(applied) != expected {
+		tests.Failed("Should have applied settings:\n%s", applied)
+	}
+	tests.Passed("Should have applied settings")
+
+	if reapplied := sshd.Apply(applied, config); string(reapplied) != expected {
+		tests.Failed("Should have produced same config when reapplied:\n%s", reapplied)
+	}
+	tests.Passed("Should have produced same config when reapplied")
+
+	if restored := sshd.Apply(applied, sshd.Config{}); string(restored) != sshdConfig {
+		tests.Failed("Should have restored replaced settings:\n%s", restored)
+	}
+	tests.Passed("Should have restored replaced settings")
+}
+
+func TestHarden(t *testing.T) {
+	key := publicKey()
+	disabled := false
+
+	executor := exectest.New()
+	executor.Expect("id -un").Stdout("deploy\n")
+	executor.Expect("getent passwd deploy").Stdout("deploy:x:1000:1000::/home/deploy:/bin/bash\n")
+	executor.Expect("sudo cat /home/deploy/.ssh/authorized_keys").Stdout(string(ssh.MarshalAuthorizedKey(key)))
+	executor.Expect("sudo cat /etc/ssh/sshd_config").Stdout(sshdConfig)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/ssh && cat > /etc/ssh/sshd_config\.box-check\.box-tmp && chmod 0600 `)
+	executor.Expect("sudo /usr/sbin/sshd -t -f /etc/ssh/sshd_config.box-check")
+	executor.Expect("sudo cp -p /etc/ssh/sshd_config /etc/ssh/sshd_config.bak && sudo mv -f /etc/ssh/sshd_config.box-check /etc/ssh/sshd_config")
+	executor.Expect("sudo systemctl reload ssh || sudo systemctl reload sshd")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	harden := sshd.Harden{
+		Config:     sshd.Config{PasswordAuthentication: &disabled, AllowUsers: []string{"deploy", "ops*@10.0.0.*"}},
+		Keys:       []ssh.PublicKey{key},
+		InitSystem: facts.Systemd,
+		Privilege:  "sudo ",
+	}
+
+	if err := harden.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully hardened sshd: %+q", err)
+	}
+	tests.Passed("Should have succcesfully hardened sshd")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+}
+
+func TestHardenLockout(t *testing.T) {
+	disabled := false
+
+	executor := exectest.New()
+	executor.Expect("getent passwd deploy").Stdout("deploy:x:1000:1000::/home/deploy:/bin/bash\n")
+	executor.Expect("cat /home/deploy/.ssh/authorized_keys").Stdout(string(ssh.MarshalAuthorizedKey(publicKey())))
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	for _, harden := range []sshd.Harden{
+		{User: "deploy", Config: sshd.Config{AllowUsers: []string{"ops"}}},
+		{User: "root", Config: sshd.Config{PermitRootLogin: sshd.RootLoginNo}},
+		{User: "deploy", Config: sshd.Config{Ciphers: []string{"3des-cbc"}}},
+		{User: "deploy", Config: sshd.Config{PasswordAuthentication: &disabled}, Keys: []ssh.PublicKey{publicKey()}},
+	} {
+		err := harden.Exec(ctx)
+		if err == nil || !strings.Contains(err.Error(), sshd.ErrLockout.Error()) {
+			tests.Failed("Should have refused settings locking out %q: %+q", harden.User, err)
+		}
+	}
+	tests.Passed("Should have refused settings locking out connecting user")
+
+	for _, command := range executor.Executed() {
+		if strings.Contains(command, "sshd_config") {
+			tests.Failed("Should have left sshd_config unchanged: %q", command)
+		}
+	}
+	tests.Passed("Should have left sshd_config unchanged")
+}
+
+func TestHardenPort(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo cat /etc/ssh/sshd_config").Stdout(sshdConfig)
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/ssh && cat > /etc/ssh/sshd_config\.box-check\.box-tmp && chmod 0600 `)
+	executor.Expect("sudo /usr/sbin/sshd -t -f /etc/ssh/sshd_config.box-check")
+	executor.Expect("sudo semanage port -a -t ssh_port_t -p tcp 2222 || sudo semanage port -m -t ssh_port_t -p tcp 2222")
+	executor.Expect("sudo firewall-cmd --permanent --list-ports").Stdout("\n")
+	executor.Expect("sudo firewall-cmd --permanent --list-rich-rules").Stdout("\n")
+	executor.Expect("sudo firewall-cmd --permanent --add-port=2222/tcp")
+	executor.Expect("sudo firewall-cmd --reload")
+	executor.Expect("sudo cp -p /etc/ssh/sshd_config /etc/ssh/sshd_config.bak && sudo mv -f /etc/ssh/sshd_config.box-check /etc/ssh/sshd_config")
+	executor.Expect("sudo systemctl reload ssh || sudo systemctl reload sshd")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	harden := sshd.Harden{
+		User:       "deploy",
+		Config:     sshd.Config{Port: 2222},
+		InitSystem: facts.Systemd,
+		Firewall:   facts.Firewalld,
+		SELinux:    facts.SELinuxEnforcing,
+		Privilege:  "sudo ",
+	}
+
+	if err := harden.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully changed sshd port: %+q", err)
+	}
+	tests.Passed("Should have succcesfully changed sshd port")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have opened port before reloading sshd: %+q", err)
+	}
+	tests.Passed("Should have opened port before reloading sshd")
+}
+
+// publicKey returns a new public key.
+func publicKey() ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		tests.Failed("Should have succcesfully generated key: %+q", err)
+	}
+
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		tests.Failed("Should have succcesfully converted key: %+q", err)
+	}
+
+	return key
+}
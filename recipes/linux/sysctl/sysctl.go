@@ -0,0 +1,258 @@
+// Package sysctl tunes the kernel of linux hosts, persisting sysctl settings as drop-ins in
+// /etc/sysctl.d and kernel modules in /etc/modules-load.d, applying both live and verifying
+// the settings through /proc/sys.
+package sysctl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidName    = errors.New("Name must only contain letters, digits and any of _.-")
+	ErrInvalidKey     = errors.New("Invalid sysctl key")
+	ErrInvalidValue   = errors.New("Invalid sysctl value")
+	ErrInvalidModule  = errors.New("Invalid kernel module name")
+	ErrNotApplied     = errors.New("Sysctl setting was not applied")
+	ErrModuleNotFound = errors.New("Kernel module is not loaded")
+)
+
+// directories the drop-ins are written into.
+const (
+	SysctlDir  = "/etc/sysctl.d"
+	ModulesDir = "/etc/modules-load.d"
+)
+
+// DefaultName defines the name of the drop-ins written by box.
+const DefaultName = "box"
+
+// DockerModules lists the kernel modules docker requires for it's overlay storage driver
+// and bridged networking.
+var DockerModules = []string{"overlay", "br_netfilter"}
+
+// DockerSettings returns the sysctl settings docker hosts require, allowing containers to
+// route traffic and bridged traffic to pass through iptables. vm.max_map_count is raised
+// for containers like elasticsearch.
+func DockerSettings() map[string]string {
+	return map[string]string{
+		"net.ipv4.ip_forward":                 "1",
+		"net.bridge.bridge-nf-call-iptables":  "1",
+		"net.bridge.bridge-nf-call-ip6tables": "1",
+		"vm.max_map_count":                    "262144",
+	}
+}
+
+// ProcPath returns the path of the key within /proc/sys.
+func ProcPath(key string) string {
+	return path.Join("/proc/sys", strings.Replace(key, ".", "/", -1))
+}
+
+// RenderSettings returns the contents of a sysctl.d drop-in with the settings, sorted so
+// the same settings always render the same file.
+func RenderSettings(settings map[string]string) []byte {
+	var out bytes.Buffer
+	out.WriteString("# Managed by box, changes will be overwritten.\n")
+
+	for _, key := range sortedKeys(settings) {
+		fmt.Fprintf(&out, "%s = %s\n", key, settings[key])
+	}
+
+	return out.Bytes()
+}
+
+// RenderModules returns the contents of a modules-load.d drop-in with the modules.
+func RenderModules(modules []string) []byte {
+	var out bytes.Buffer
+	out.WriteString("# Managed by box, changes will be overwritten.\n")
+
+	for _, module := range modules {
+		out.WriteString(module + "\n")
+	}
+
+	return out.Bytes()
+}
+
+//===============================================================================================================
+
+// Tune implements the ops.Op interface, loading the modules and applying the settings to
+// the host, persisting both so they survive reboots. Modules are loaded first as some
+// settings, like the bridge-nf-call ones, only exist once their module is loaded.
+type Tune struct {
+	// Name sets the name of the drop-ins, it defaults to DefaultName. The sysctl drop-in
+	// is prefixed with 99- so it's read after the distribution's own.
+	Name string
+
+	Settings map[string]string
+	Modules  []string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Validate returns an error if any of the settings or modules is invalid.
+func (t Tune) Validate() error {
+	if t.Name != "" && strings.Trim(t.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.-") != "" {
+		return fmt.Errorf("%s: %q", ErrInvalidName, t.Name)
+	}
+
+	for key, value := range t.Settings {
+		if key == "" || strings.Trim(key, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.-") != "" || strings.Contains(key, "..") {
+			return fmt.Errorf("%s: %q", ErrInvalidKey, key)
+		}
+
+		if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "'\n") {
+			return fmt.Errorf("%s: %q", ErrInvalidValue, value)
+		}
+	}
+
+	for _, module := range t.Modules {
+		if module == "" || strings.Trim(module, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+			return fmt.Errorf("%s: %q", ErrInvalidModule, module)
+		}
+	}
+
+	return nil
+}
+
+// Exec executes giving recipe for tuning the kernel.
+func (t Tune) Exec(ctx context.CancelContext) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	name := t.Name
+	if name == "" {
+		name = DefaultName
+	}
+
+	if len(t.Modules) != 0 {
+		if err := t.place(ctx, path.Join(ModulesDir, name+".conf"), RenderModules(t.Modules)); err != nil {
+			return err
+		}
+
+		for _, module := range t.Modules {
+			if err := t.load(ctx, module); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(t.Settings) == 0 {
+		return nil
+	}
+
+	if err := t.place(ctx, path.Join(SysctlDir, "99-"+name+".conf"), RenderSettings(t.Settings)); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(t.Settings) {
+		if err := t.apply(ctx, key, t.Settings[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// place writes the drop-in if it's contents differ.
+func (t Tune) place(ctx context.CancelContext, file string, content []byte) error {
+	exists, err := exec.FileExists(ctx, file, t.Privilege)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		existing, err := exec.ReadFile(ctx, file)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(existing, content) {
+			return nil
+		}
+	}
+
+	return exec.WriteFile(ctx, file, content, 0644, t.Privilege)
+}
+
+// load loads the module unless it's loaded or built into the kernel, both of which list
+// it within /sys/module.
+func (t Tune) load(ctx context.CancelContext, module string) error {
+	loaded, err := exec.FileExists(ctx, path.Join("/sys/module", module), "")
+	if err != nil || loaded {
+		return err
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%smodprobe %s", t.Privilege, module), t.DoWithCmd); err != nil {
+		return err
+	}
+
+	if loaded, err = exec.FileExists(ctx, path.Join("/sys/module", module), ""); err != nil {
+		return err
+	}
+
+	if !loaded {
+		return fmt.Errorf("%s: %q", ErrModuleNotFound, module)
+	}
+
+	return nil
+}
+
+// apply sets the key live unless /proc/sys already holds the value, verifying it was set.
+func (t Tune) apply(ctx context.CancelContext, key string, value string) error {
+	current, err := Value(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if current == normalize(value) {
+		return nil
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%ssysctl -w '%s=%s'", t.Privilege, key, value), t.DoWithCmd); err != nil {
+		return err
+	}
+
+	if current, err = Value(ctx, key); err != nil {
+		return err
+	}
+
+	if current != normalize(value) {
+		return fmt.Errorf("%s: %s is %q instead of %q", ErrNotApplied, key, current, value)
+	}
+
+	return nil
+}
+
+// Value returns the live value of the key read from /proc/sys, with values made of
+// multiple fields, like net.ipv4.ip_local_port_range, separated by single spaces.
+func Value(ctx context.CancelContext, key string) (string, error) {
+	data, err := exec.ReadFile(ctx, ProcPath(key))
+	if err != nil {
+		return "", err
+	}
+
+	return normalize(string(data)), nil
+}
+
+func normalize(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func sortedKeys(settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
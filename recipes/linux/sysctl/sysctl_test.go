@@ -0,0 +1,81 @@
+package sysctl_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/linux/sysctl"
+	"github.com/influx6/faux/tests"
+)
+
+func TestTune(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("sudo test -e /etc/modules-load.d/box.conf").Exit(1)
+	executor.Expect("sudo sh -c 'mkdir -p /etc/modules-load.d && cat > /etc/modules-load.d/box.conf.box-tmp && chmod 0644 /etc/modules-load.d/box.conf.box-tmp && mv -f /etc/modules-load.d/box.conf.box-tmp /etc/modules-load.d/box.conf'")
+	executor.Expect("test -e /sys/module/overlay")
+	executor.Expect("test -e /sys/module/br_netfilter").Exit(1).Times(1)
+	executor.Expect("sudo modprobe br_netfilter")
+	executor.Expect("test -e /sys/module/br_netfilter")
+	executor.Expect("sudo test -e /etc/sysctl.d/99-box.conf")
+	executor.Expect("cat /etc/sysctl.d/99-box.conf").Stdout("net.ipv4.ip_forward = 0\n")
+	executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /etc/sysctl.d && cat > /etc/sysctl.d/99-box\.conf\.box-tmp `)
+	executor.Expect("cat /proc/sys/net/ipv4/ip_forward").Stdout("0\n").Times(1)
+	executor.Expect("sudo sysctl -w 'net.ipv4.ip_forward=1'")
+	executor.Expect("cat /proc/sys/net/ipv4/ip_forward").Stdout("1\n")
+	executor.Expect("cat /proc/sys/net/ipv4/ip_local_port_range").Stdout("1024\t65000\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	tune := sysctl.Tune{
+		Settings: map[string]string{
+			"net.ipv4.ip_forward":          "1",
+			"net.ipv4.ip_local_port_range": "1024 65000",
+		},
+		Modules:   sysctl.DockerModules,
+		Privilege: "sudo ",
+	}
+
+	if err := tune.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully tuned kernel: %+q", err)
+	}
+	tests.Passed("Should have succcesfully tuned kernel")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+
+	if calls := executor.Calls(); string(calls[1].Stdin) != "# Managed by box, changes will be overwritten.\noverlay\nbr_netfilter\n" {
+		tests.Failed("Should have persisted modules: %q", calls[1].Stdin)
+	}
+	tests.Passed("Should have persisted modules")
+}
+
+func TestTuneNotApplied(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("test -e /etc/sysctl.d/99-box.conf")
+	executor.Expect("cat /etc/sysctl.d/99-box.conf").Stdout(string(sysctl.RenderSettings(map[string]string{"vm.max_map_count": "262144"})))
+	executor.Expect("cat /proc/sys/vm/max_map_count").Stdout("65530\n")
+	executor.Expect("sysctl -w 'vm.max_map_count=262144'")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	tune := sysctl.Tune{Settings: map[string]string{"vm.max_map_count": "262144"}}
+
+	if err := tune.Exec(ctx); err == nil || !strings.Contains(err.Error(), sysctl.ErrNotApplied.Error()) {
+		tests.Failed("Should have failed verifying setting: %+q", err)
+	}
+	tests.Passed("Should have failed verifying setting")
+}
@@ -0,0 +1,258 @@
+// Package systemd installs long running services as systemd units, rendering unit files
+// from a typed spec and managing them with systemctl through the default executor.
+package systemd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidUnitName = errors.New("Unit name must only contain letters, digits and any of :_.@-")
+	ErrNoExecStart     = errors.New("Unit requires ExecStart")
+	ErrUnitNotActive   = errors.New("Unit failed to become active")
+)
+
+// UnitDir defines the directory unit files are installed into.
+const UnitDir = "/etc/systemd/system"
+
+// DefaultTimeout defines how long a unit has to become active once started.
+const DefaultTimeout = 30 * time.Second
+
+// Unit defines the spec of a systemd service unit.
+type Unit struct {
+	// Name sets the name of the unit, `.service` is appended if it has no suffix.
+	Name        string
+	Description string
+
+	// After and Wants list the units the service is ordered after and depends on,
+	// like `network-online.target`.
+	After []string
+	Wants []string
+
+	ExecStart        string
+	Environment      map[string]string
+	User             string
+	WorkingDirectory string
+
+	// Restart sets when the service is restarted, like `always`, it defaults to `on-failure`.
+	Restart    string
+	RestartSec time.Duration
+
+	// WantedBy lists the targets the unit is enabled for, it defaults to `multi-user.target`.
+	WantedBy []string
+}
+
+// FileName returns the name of the unit's file.
+func (u Unit) FileName() string {
+	if strings.Contains(u.Name, ".") {
+		return u.Name
+	}
+
+	return u.Name + ".service"
+}
+
+// Validate returns an error if the unit can not be rendered.
+func (u Unit) Validate() error {
+	if u.Name == "" || strings.Trim(u.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789:_.@-") != "" {
+		return fmt.Errorf("%s: %q", ErrInvalidUnitName, u.Name)
+	}
+
+	if strings.TrimSpace(u.ExecStart) == "" {
+		return ErrNoExecStart
+	}
+
+	return nil
+}
+
+// Render returns the contents of the unit's file. Environment variables are sorted so
+// the same spec always renders the same file.
+func (u Unit) Render() []byte {
+	var unit bytes.Buffer
+
+	unit.WriteString("[Unit]\n")
+	if u.Description != "" {
+		fmt.Fprintf(&unit, "Description=%s\n", u.Description)
+	}
+
+	if len(u.After) != 0 {
+		fmt.Fprintf(&unit, "After=%s\n", strings.Join(u.After, " "))
+	}
+
+	if len(u.Wants) != 0 {
+		fmt.Fprintf(&unit, "Wants=%s\n", strings.Join(u.Wants, " "))
+	}
+
+	unit.WriteString("\n[Service]\n")
+	fmt.Fprintf(&unit, "ExecStart=%s\n", u.ExecStart)
+
+	keys := make([]string, 0, len(u.Environment))
+	for key := range u.Environment {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&unit, "Environment=%s\n", quote(key+"="+u.Environment[key]))
+	}
+
+	if u.User != "" {
+		fmt.Fprintf(&unit, "User=%s\n", u.User)
+	}
+
+	if u.WorkingDirectory != "" {
+		fmt.Fprintf(&unit, "WorkingDirectory=%s\n", u.WorkingDirectory)
+	}
+
+	restart := u.Restart
+	if restart == "" {
+		restart = "on-failure"
+	}
+
+	fmt.Fprintf(&unit, "Restart=%s\n", restart)
+	if u.RestartSec > 0 {
+		fmt.Fprintf(&unit, "RestartSec=%d\n", int(u.RestartSec.Seconds()))
+	}
+
+	wantedBy := u.WantedBy
+	if len(wantedBy) == 0 {
+		wantedBy = []string{"multi-user.target"}
+	}
+
+	fmt.Fprintf(&unit, "\n[Install]\nWantedBy=%s\n", strings.Join(wantedBy, " "))
+
+	return unit.Bytes()
+}
+
+// quote returns the value double quoted, escaping characters systemd treats specially.
+func quote(val string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%")
+	return `"` + replacer.Replace(val) + `"`
+}
+
+//===============================================================================================================
+
+// UnitInstall implements the ops.Op interface, installing the unit and making sure it's
+// enabled and running. The unit's file is only written if it's contents differ, in which
+// case systemd is reloaded and the service restarted to pick up the changes.
+type UnitInstall struct {
+	Unit Unit
+
+	// Timeout sets how long the unit has to become active, it defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for installing the unit.
+func (ui UnitInstall) Exec(ctx context.CancelContext) error {
+	if err := ui.Unit.Validate(); err != nil {
+		return err
+	}
+
+	name := ui.Unit.FileName()
+	unitFile := path.Join(UnitDir, name)
+	rendered := ui.Unit.Render()
+
+	exists, err := exec.FileExists(ctx, unitFile, ui.Privilege)
+	if err != nil {
+		return err
+	}
+
+	changed := true
+	if exists {
+		existing, err := exec.ReadFile(ctx, unitFile)
+		if err != nil {
+			return err
+		}
+
+		changed = !bytes.Equal(existing, rendered)
+	}
+
+	start := "start"
+
+	if changed {
+		if err := exec.WriteFile(ctx, unitFile, rendered, 0644, ui.Privilege); err != nil {
+			return err
+		}
+
+		if err := exec.Run(ctx, fmt.Sprintf("%ssystemctl daemon-reload", ui.Privilege), ui.DoWithCmd); err != nil {
+			return err
+		}
+
+		start = "restart"
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%ssystemctl enable %s", ui.Privilege, name), ui.DoWithCmd); err != nil {
+		return err
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%ssystemctl %s %s", ui.Privilege, start, name), ui.DoWithCmd); err != nil {
+		return err
+	}
+
+	timeout := ui.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return WaitActive(ctx, name, timeout)
+}
+
+// ActiveState returns the active state of the unit as reported by `systemctl is-active`,
+// like `active`, `activating` or `failed`.
+func ActiveState(ctx context.CancelContext, name string) (string, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command("systemctl is-active "+name), exec.Sync(), exec.Output(&outs))
+
+	// is-active exits with a non-zero status for units which are not active, while still
+	// reporting their state.
+	if err := cmd.Exec(ctx); err != nil {
+		if _, ok := err.(*exec.CommandError); !ok || outs.Len() == 0 {
+			return "", err
+		}
+	}
+
+	return strings.TrimSpace(outs.String()), nil
+}
+
+// WaitActive waits for the unit to become active, polling it's state every second while
+// it's activating. It fails once the unit reaches any other state or the timeout elapses.
+func WaitActive(ctx context.CancelContext, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		state, err := ActiveState(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		switch state {
+		case "active":
+			return nil
+		case "activating", "reloading":
+		default:
+			return fmt.Errorf("%s: %q is %s", ErrUnitNotActive, name, state)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: %q is still %s after %s", ErrUnitNotActive, name, state, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %q is %s", ErrUnitNotActive, name, state)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package systemd_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/linux/systemd"
+	"github.com/influx6/faux/tests"
+)
+
+var unit = systemd.Unit{
+	Name:        "box",
+	Description: "box service",
+	After:       []string{"network-online.target", "docker.service"},
+	Wants:       []string{"network-online.target"},
+	ExecStart:   "/usr/local/bin/box service serve",
+	Environment: map[string]string{"BOX_HOME": "/var/lib/box", "GREETING": `say "hi" 100%`},
+	User:        "box",
+	RestartSec:  5 * time.Second,
+}
+
+func TestRender(t *testing.T) {
+	expected := `[Unit]
+Description=box service
+After=network-online.target docker.service
+Wants=network-online.target
+
+[Service]
+ExecStart=/usr/local/bin/box service serve
+Environment="BOX_HOME=/var/lib/box"
+Environment="GREETING=say \"hi\" 100%%"
+User=box
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	if rendered := string(unit.Render()); rendered != expected {
+		tests.Failed("Should have rendered unit:\n%s", rendered)
+	}
+	tests.Passed("Should have rendered unit")
+}
+
+func TestUnitInstall(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo test -e /etc/systemd/system/box.service").Exit(1)
+	executor.Expect("sudo sh -c 'mkdir -p /etc/systemd/system && cat > /etc/systemd/system/box.service.box-tmp && chmod 0644 /etc/systemd/system/box.service.box-tmp && mv -f /etc/systemd/system/box.service.box-tmp /etc/systemd/system/box.service'")
+	executor.Expect("sudo systemctl daemon-reload")
+	executor.Expect("sudo systemctl enable box.service")
+	executor.Expect("sudo systemctl restart box.service")
+	executor.Expect("systemctl is-active box.service").Stdout("active\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (systemd.UnitInstall{Unit: unit, Privilege: "sudo "}).Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully installed unit: %+q", err)
+	}
+	tests.Passed("Should have succcesfully installed unit")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestUnitInstallUnchanged(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("test -e /etc/systemd/system/box.service")
+	executor.Expect("cat /etc/systemd/system/box.service").Stdout(string(unit.Render()))
+	executor.Expect("systemctl enable box.service")
+	executor.Expect("systemctl start box.service")
+	executor.Expect("systemctl is-active box.service").Stdout("failed\n").Exit(3)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (systemd.UnitInstall{Unit: unit}).Exec(ctx); err == nil {
+		tests.Failed("Should have failed for unit which is not active")
+	}
+	tests.Passed("Should have failed for unit which is not active")
+
+	if unexpected := executor.Unexpected(); len(unexpected) != 0 {
+		tests.Failed("Should have left unchanged unit file alone: %+q", unexpected)
+	}
+	tests.Passed("Should have left unchanged unit file alone")
+}
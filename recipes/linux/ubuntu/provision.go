@@ -21,7 +21,11 @@ var (
 
 // custom executors.
 var (
-	SudoInstaller         = exec.New(exec.Command("if ! type sudo; then apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y sudo; fi"))
+	SudoInstaller = exec.New(exec.Command("if ! type sudo; then apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y sudo; fi"))
+
+	// DockerSourceInstaller installs docker with the unverified script from https://get.docker.com.
+	//
+	// Deprecated: Use dockerapt.Installer which verifies the repository key and installed version.
 	DockerSourceInstaller = exec.New(exec.Command("wget -nv -O - https://get.docker.com/ | sh"))
 )
 
@@ -155,7 +159,7 @@ func (pkg *PackageInstaller) Exec(ctx context.CancelContext) error {
 
 	switch {
 	case pkg.debian:
-		command = fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %+s -y  %s", pkg.Name, pkg.Name)
+		command = fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %+s -y  %s", pkg.Action, pkg.Name)
 	case pkg.upstartUbuntu:
 		if pkg.Action == PurgeAction {
 			pkg.Action = RemoveAction
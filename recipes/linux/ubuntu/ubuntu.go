@@ -2,7 +2,9 @@ package ubuntu
 
 import (
 	"github.com/influx6/box"
+	"github.com/influx6/box/recipes/exec/facts"
 	"github.com/influx6/box/recipes/exec/osinfo"
+	"github.com/influx6/box/recipes/linux/dockerapt"
 	"github.com/influx6/faux/context"
 	"github.com/influx6/faux/ops"
 )
@@ -16,7 +18,11 @@ var (
 // ubuntuProvisioner implements ops.Op interface and contains necessary procedures to provision a
 // ubuntu linux vm/system for app deployment with box.
 type ubuntuProvisioner struct {
-	Info osinfo.Info `json:"os_info"`
+	Info  osinfo.Info `json:"os_info"`
+	Facts facts.Facts `json:"facts"`
+
+	// DockerVersion sets the constraint on the docker release installed, like `>=20.10 <25`.
+	DockerVersion string `json:"docker_version"`
 }
 
 func (ubp *ubuntuProvisioner) Exec(ctx context.CancelContext) error {
@@ -52,8 +58,21 @@ func (ubp *ubuntuProvisioner) Exec(ctx context.CancelContext) error {
 		return err
 	}
 
-	// Call docker installation from https://get.docker.com
-	if err := DockerSourceInstaller.Exec(ctx); err != nil {
+	// Docker is already installed, skip adding it's repository.
+	if ubp.Facts.DockerInstalled && dockerapt.Satisfies(ubp.Facts.DockerVersion, ubp.DockerVersion) {
+		return nil
+	}
+
+	// Install docker from docker's apt repository after verifying it's key.
+	installer := dockerapt.Installer{
+		Distro:     "ubuntu",
+		Codename:   ubp.Info.Codename(),
+		Arch:       ubp.Facts.Arch,
+		Constraint: ubp.DockerVersion,
+		Privilege:  "sudo ",
+	}
+
+	if err := installer.Exec(ctx); err != nil {
 		return err
 	}
 
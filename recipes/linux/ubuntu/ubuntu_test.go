@@ -0,0 +1,101 @@
+package ubuntu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/tests"
+)
+
+func TestUbuntuProvisioner(t *testing.T) {
+	expected := []string{
+		"if ! type sudo; then apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y sudo; fi",
+		"sudo apt-get -y update",
+		"DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  git",
+		"DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  curl",
+		"DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  wget",
+		"DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  openssh",
+		"DEBIAN_FRONTEND=noninteractive sudo -E apt-get install -y  apt-transport-https",
+	}
+
+	executor := exectest.New().InOrder()
+	for _, command := range expected {
+		executor.Expect(command)
+	}
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	provisioner := &ubuntuProvisioner{
+		Facts:         facts.Facts{DockerInstalled: true, DockerVersion: "20.10.7"},
+		DockerVersion: ">=20.10 <25",
+	}
+
+	if err := provisioner.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully provisioned host: %+q", err)
+	}
+	tests.Passed("Should have succcesfully provisioned host")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestUbuntuProvisionerStopsOnFailure(t *testing.T) {
+	executor := exectest.New().AllowUnexpected()
+	executor.Expect("sudo apt-get -y update").Exit(100).Stderr("E: Could not get lock /var/lib/apt/lists/lock\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	err := (&ubuntuProvisioner{}).Exec(ctx)
+	if cmdErr, ok := err.(*exec.CommandError); !ok || cmdErr.ExitCode != 100 {
+		tests.Failed("Should have failed with apt-get exit code: %+q", err)
+	}
+	tests.Passed("Should have failed with apt-get exit code")
+
+	if executed := executor.Executed(); len(executed) != 2 {
+		tests.Failed("Should have stopped after failed command: %+q", executed)
+	}
+	tests.Passed("Should have stopped after failed command")
+}
+
+func TestPackageInstallerActions(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("DEBIAN_FRONTEND=noninteractive sudo -E apt-get remove -y  git")
+	executor.Expect("DEBIAN_FRONTEND=noninteractive sudo -E apt-get purge -y  curl")
+	executor.Expect(`DEBIAN_FRONTEND=noninteractive sudo -E apt-get remove -y -o Dpkg::Options::="--force-confnew" wget`)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	for _, pkg := range []*PackageInstaller{
+		PkgPartial(PkgCommand("git", RemoveAction), Debian())(),
+		PkgPartial(PkgCommand("curl", PurgeAction), Debian())(),
+		PkgPartial(PkgCommand("wget", PurgeAction), UbuntuUpstart())(),
+	} {
+		if err := pkg.Exec(ctx); err != nil {
+			tests.Failed("Should have succcesfully executed %s of %q: %+q", pkg.Action, pkg.Name, err)
+		}
+	}
+	tests.Passed("Should have succcesfully executed package actions")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have passed the package action to apt-get: %+q", err)
+	}
+	tests.Passed("Should have passed the package action to apt-get")
+}
@@ -0,0 +1,111 @@
+package users
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+	"golang.org/x/crypto/ssh"
+)
+
+// errors
+var (
+	ErrInvalidKey = errors.New("Invalid authorized ssh key")
+)
+
+// AuthorizedKeys implements the ops.Op interface, adding the public keys missing from
+// the user's ~/.ssh/authorized_keys. Keys already present, compared by their key rather
+// than their comment or options, and all other lines of the file are left unchanged.
+type AuthorizedKeys struct {
+	User string
+
+	// Keys lists the keys in authorized_keys format, like `ssh-ed25519 AAAA... alex@laptop`.
+	Keys []string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for authorizing the keys.
+func (ak AuthorizedKeys) Exec(ctx context.CancelContext) error {
+	if err := ValidName(ak.User); err != nil {
+		return err
+	}
+
+	wanted := make([]ssh.PublicKey, 0, len(ak.Keys))
+	for _, key := range ak.Keys {
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return fmt.Errorf("%s: %s", ErrInvalidKey, err)
+		}
+
+		wanted = append(wanted, parsed)
+	}
+
+	home, err := HomeDir(ctx, ak.User)
+	if err != nil {
+		return err
+	}
+
+	sshDir := path.Join(home, ".ssh")
+	keysFile := path.Join(sshDir, "authorized_keys")
+
+	exists, err := exec.FileExists(ctx, keysFile, ak.Privilege)
+	if err != nil {
+		return err
+	}
+
+	var existing []byte
+	if exists {
+		// The file usually is only readable by the user.
+		var outs bytes.Buffer
+		catCmd := exec.New(exec.Command(fmt.Sprintf("%scat %s", ak.Privilege, shellQuote(keysFile))), exec.Sync(), exec.Output(&outs))
+
+		if err := catCmd.Exec(ctx); err != nil {
+			return err
+		}
+
+		existing = outs.Bytes()
+	}
+
+	authorized := map[string]bool{}
+	for rest := existing; len(rest) != 0; {
+		key, _, _, next, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			break
+		}
+
+		authorized[string(key.Marshal())] = true
+		rest = next
+	}
+
+	var missing []string
+	for index, key := range wanted {
+		if !authorized[string(key.Marshal())] {
+			missing = append(missing, strings.TrimSpace(ak.Keys[index]))
+			authorized[string(key.Marshal())] = true
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	content := existing
+	if len(content) != 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+
+	content = append(content, []byte(strings.Join(missing, "\n")+"\n")...)
+
+	if err := exec.WriteFile(ctx, keysFile, content, 0600, ak.Privilege); err != nil {
+		return err
+	}
+
+	// sshd ignores keys within directories writable by other users.
+	return exec.Run(ctx, fmt.Sprintf("%schmod 0700 %s && %schown %s: %s %s", ak.Privilege, shellQuote(sshDir), ak.Privilege, ak.User, shellQuote(sshDir), shellQuote(keysFile)), ak.DoWithCmd)
+}
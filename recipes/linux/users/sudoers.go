@@ -0,0 +1,95 @@
+package users
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidDropIn = errors.New("Sudoers drop-in name must only contain letters, digits, underscores and dashes")
+	ErrNoRules       = errors.New("Sudoers drop-in requires at least one rule")
+)
+
+// SudoersDir defines the directory sudoers drop-ins are installed into.
+const SudoersDir = "/etc/sudoers.d"
+
+// Sudoers implements the ops.Op interface, installing a sudoers drop-in containing the
+// rules. The drop-in is checked with `visudo -c` before it's moved into place, as a
+// broken sudoers file locks everyone out of sudo.
+type Sudoers struct {
+	// Name sets the file name of the drop-in, sudo ignores files containing a `.` or
+	// ending in `~`, so these are rejected.
+	Name string
+
+	// Rules lists the sudoers rules, like `deploy ALL=(root) NOPASSWD: /usr/bin/docker`.
+	Rules []string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Render returns the contents of the drop-in.
+func (s Sudoers) Render() []byte {
+	var content bytes.Buffer
+	content.WriteString("# Managed by box, changes will be overwritten.\n")
+
+	for _, rule := range s.Rules {
+		content.WriteString(strings.TrimSpace(rule))
+		content.WriteString("\n")
+	}
+
+	return content.Bytes()
+}
+
+// Exec executes giving recipe for installing the drop-in.
+func (s Sudoers) Exec(ctx context.CancelContext) error {
+	if s.Name == "" || strings.Trim(s.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+		return fmt.Errorf("%s: %q", ErrInvalidDropIn, s.Name)
+	}
+
+	if len(s.Rules) == 0 {
+		return ErrNoRules
+	}
+
+	dropIn := path.Join(SudoersDir, s.Name)
+	content := s.Render()
+
+	exists, err := exec.FileExists(ctx, dropIn, s.Privilege)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		// Drop-ins are only readable by root.
+		var outs bytes.Buffer
+		catCmd := exec.New(exec.Command(fmt.Sprintf("%scat %s", s.Privilege, dropIn)), exec.Sync(), exec.Output(&outs))
+
+		if err := catCmd.Exec(ctx); err != nil {
+			return err
+		}
+
+		if bytes.Equal(outs.Bytes(), content) {
+			return nil
+		}
+	}
+
+	// Written with a `.` in it's name, sudo ignores the drop-in until it's validated.
+	check := dropIn + ".box-check"
+	if err := exec.WriteFile(ctx, check, content, 0440, s.Privilege); err != nil {
+		return err
+	}
+
+	if err := exec.Run(ctx, fmt.Sprintf("%svisudo -c -f %s", s.Privilege, check), s.DoWithCmd); err != nil {
+		exec.Run(ctx, fmt.Sprintf("%srm -f %s", s.Privilege, check), s.DoWithCmd)
+		return err
+	}
+
+	return exec.Run(ctx, fmt.Sprintf("%smv -f %s %s", s.Privilege, check, dropIn), s.DoWithCmd)
+}
@@ -0,0 +1,304 @@
+// Package users manages the users, groups, authorized ssh keys and sudoers rules of linux
+// hosts. Each recipe checks the host's current state with getent and id first, changing
+// only what differs so recipes can be run repeatedly.
+package users
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrInvalidName   = errors.New("User and group names must start with a letter or underscore and only contain letters, digits, underscores and dashes")
+	ErrUserNotFound  = errors.New("User does not exist")
+	ErrGroupNotFound = errors.New("Group does not exist")
+)
+
+// exit codes reported for missing users and groups.
+const (
+	getentNotFound = 2
+	idNotFound     = 1
+)
+
+// ValidName returns an error if the name is not a portable user or group name.
+func ValidName(name string) error {
+	if name == "" || len(name) > 32 || strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		return fmt.Errorf("%s: %q", ErrInvalidName, name)
+	}
+
+	return nil
+}
+
+// lookup runs the command returning it's output, or false if it exited with missingCode.
+func lookup(ctx context.CancelContext, command string, missingCode int) (string, bool, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(command), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok && cmdErr.ExitCode == missingCode {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	return strings.TrimSpace(outs.String()), true, nil
+}
+
+// GroupExists returns true if the group exists on the host.
+func GroupExists(ctx context.CancelContext, name string) (bool, error) {
+	_, found, err := lookup(ctx, "getent group "+name, getentNotFound)
+	return found, err
+}
+
+// UserExists returns true if the user exists on the host.
+func UserExists(ctx context.CancelContext, name string) (bool, error) {
+	_, found, err := lookup(ctx, "id -u "+name, idNotFound)
+	return found, err
+}
+
+// Groups returns the names of the groups the user belongs to.
+func Groups(ctx context.CancelContext, name string) ([]string, error) {
+	groups, found, err := lookup(ctx, "id -nG "+name, idNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s: %q", ErrUserNotFound, name)
+	}
+
+	return strings.Fields(groups), nil
+}
+
+// HomeDir returns the home directory of the user from it's passwd entry.
+func HomeDir(ctx context.CancelContext, name string) (string, error) {
+	entry, found, err := lookup(ctx, "getent passwd "+name, getentNotFound)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Split(entry, ":")
+	if !found || len(fields) < 7 {
+		return "", fmt.Errorf("%s: %q", ErrUserNotFound, name)
+	}
+
+	return fields[5], nil
+}
+
+//===============================================================================================================
+
+// GroupAdd implements the ops.Op interface, creating the group if it does not exist. It
+// uses groupadd, falling back to busybox's addgroup on hosts without it, like alpine.
+type GroupAdd struct {
+	Name string
+
+	// System sets the group to be created as a system group.
+	System bool
+
+	// GID sets the id of the group, if zero the next free id is used.
+	GID int
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for creating the group.
+func (ga GroupAdd) Exec(ctx context.CancelContext) error {
+	if err := ValidName(ga.Name); err != nil {
+		return err
+	}
+
+	exists, err := GroupExists(ctx, ga.Name)
+	if err != nil || exists {
+		return err
+	}
+
+	var groupadd, addgroup []string
+	if ga.System {
+		groupadd, addgroup = append(groupadd, "-r"), append(addgroup, "-S")
+	}
+
+	if ga.GID != 0 {
+		gid := strconv.Itoa(ga.GID)
+		groupadd, addgroup = append(groupadd, "-g", gid), append(addgroup, "-g", gid)
+	}
+
+	groupadd, addgroup = append(groupadd, ga.Name), append(addgroup, ga.Name)
+
+	return exec.Run(ctx, fallback(ga.Privilege, "groupadd", groupadd, "addgroup", addgroup), ga.DoWithCmd)
+}
+
+//===============================================================================================================
+
+// UserAdd implements the ops.Op interface, creating the user if it does not exist. It
+// uses useradd, falling back to busybox's adduser on hosts without it, like alpine.
+// Existing users are left unchanged, use GroupMember to add them to groups.
+type UserAdd struct {
+	Name string
+
+	// System sets the user to be created as a system user, without a login shell unless
+	// Shell is set.
+	System bool
+
+	// UID sets the id of the user, if zero the next free id is used.
+	UID int
+
+	Home  string
+	Shell string
+
+	// Groups lists the supplementary groups of the user, they must already exist.
+	Groups []string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for creating the user.
+func (ua UserAdd) Exec(ctx context.CancelContext) error {
+	if err := ValidName(ua.Name); err != nil {
+		return err
+	}
+
+	exists, err := UserExists(ctx, ua.Name)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if err := exec.Run(ctx, ua.command(), ua.DoWithCmd); err != nil {
+			return err
+		}
+	}
+
+	if len(ua.Groups) == 0 {
+		return nil
+	}
+
+	member := GroupMember{
+		User:      ua.Name,
+		Groups:    ua.Groups,
+		Privilege: ua.Privilege,
+		DoWithCmd: ua.DoWithCmd,
+	}
+
+	return member.Exec(ctx)
+}
+
+func (ua UserAdd) command() string {
+	shell := ua.Shell
+	if shell == "" && ua.System {
+		shell = "/sbin/nologin"
+	}
+
+	var useradd, adduser []string
+	if ua.System {
+		useradd, adduser = append(useradd, "-r"), append(adduser, "-S")
+	} else {
+		useradd = append(useradd, "-m")
+	}
+
+	adduser = append(adduser, "-D")
+
+	if ua.UID != 0 {
+		uid := strconv.Itoa(ua.UID)
+		useradd, adduser = append(useradd, "-u", uid), append(adduser, "-u", uid)
+	}
+
+	if ua.Home != "" {
+		home := shellQuote(ua.Home)
+		useradd, adduser = append(useradd, "-d", home), append(adduser, "-h", home)
+	}
+
+	if shell != "" {
+		shell = shellQuote(shell)
+		useradd, adduser = append(useradd, "-s", shell), append(adduser, "-s", shell)
+	}
+
+	useradd, adduser = append(useradd, ua.Name), append(adduser, ua.Name)
+
+	return fallback(ua.Privilege, "useradd", useradd, "adduser", adduser)
+}
+
+//===============================================================================================================
+
+// GroupMember implements the ops.Op interface, adding the user to the supplementary
+// groups it's not yet a member of. Memberships take effect on the user's next login.
+type GroupMember struct {
+	User   string
+	Groups []string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Exec executes giving recipe for adding the user to the groups.
+func (gm GroupMember) Exec(ctx context.CancelContext) error {
+	current, err := Groups(ctx, gm.User)
+	if err != nil {
+		return err
+	}
+
+	member := map[string]bool{}
+	for _, group := range current {
+		member[group] = true
+	}
+
+	var missing []string
+	for _, group := range gm.Groups {
+		if err := ValidName(group); err != nil {
+			return err
+		}
+
+		if !member[group] {
+			missing = append(missing, group)
+			member[group] = true
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	for _, group := range missing {
+		exists, err := GroupExists(ctx, group)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("%s: %q", ErrGroupNotFound, group)
+		}
+	}
+
+	// busybox's addgroup adds the user to one group at a time.
+	addgroups := make([]string, 0, len(missing))
+	for _, group := range missing {
+		addgroups = append(addgroups, fmt.Sprintf("%saddgroup %s %s", gm.Privilege, gm.User, group))
+	}
+
+	command := fmt.Sprintf("if command -v usermod >/dev/null 2>&1; then %susermod -aG %s %s; else %s; fi", gm.Privilege, strings.Join(missing, ","), gm.User, strings.Join(addgroups, " && "))
+
+	return exec.Run(ctx, command, gm.DoWithCmd)
+}
+
+// fallback returns a command running binary with args if it exists on the host, else
+// the alternative with it's args.
+func fallback(privilege string, binary string, args []string, alternative string, altArgs []string) string {
+	return fmt.Sprintf("if command -v %s >/dev/null 2>&1; then %s%s %s; else %s%s %s; fi", binary, privilege, binary, strings.Join(args, " "), privilege, alternative, strings.Join(altArgs, " "))
+}
+
+// shellQuote returns the value single quoted for use within shell commands.
+func shellQuote(val string) string {
+	return "'" + strings.Replace(val, "'", `'\''`, -1) + "'"
+}
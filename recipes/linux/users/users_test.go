@@ -0,0 +1,162 @@
+package users_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/linux/users"
+	"github.com/influx6/faux/tests"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestUserAdd(t *testing.T) {
+	executor := exectest.New().InOrder()
+	executor.Expect("id -u deploy").Exit(1)
+	executor.Expect("if command -v useradd >/dev/null 2>&1; then sudo useradd -m -s '/bin/bash' deploy; else sudo adduser -D -s '/bin/bash' deploy; fi")
+	executor.Expect("id -nG deploy").Stdout("deploy adm\n")
+	executor.Expect("getent group docker").Stdout("docker:x:998:\n")
+	executor.Expect("if command -v usermod >/dev/null 2>&1; then sudo usermod -aG docker deploy; else sudo addgroup deploy docker; fi")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	add := users.UserAdd{Name: "deploy", Shell: "/bin/bash", Groups: []string{"adm", "docker"}, Privilege: "sudo "}
+	if err := add.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully added user: %+q", err)
+	}
+	tests.Passed("Should have succcesfully added user")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed commands in order: %+q", err)
+	}
+	tests.Passed("Should have executed commands in order")
+}
+
+func TestGroupMemberMissingGroup(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("id -nG deploy").Stdout("deploy\n")
+	executor.Expect("getent group docker").Exit(2)
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (users.GroupMember{User: "deploy", Groups: []string{"docker"}}).Exec(ctx); err == nil {
+		tests.Failed("Should have failed to add user to missing group")
+	}
+	tests.Passed("Should have failed to add user to missing group")
+}
+
+func TestAuthorizedKeys(t *testing.T) {
+	present, added := publicKey(), publicKey()
+
+	executor := exectest.New()
+	executor.Expect("getent passwd deploy").Stdout("deploy:x:1000:1000::/home/deploy:/bin/bash\n")
+	executor.Expect("sudo test -e /home/deploy/.ssh/authorized_keys")
+	executor.Expect("sudo cat '/home/deploy/.ssh/authorized_keys'").Stdout("# keys\n" + present + " old-comment")
+	write := executor.ExpectRegexp(`^sudo sh -c 'mkdir -p /home/deploy/\.ssh && cat > /home/deploy/\.ssh/authorized_keys\.box-tmp && chmod 0600 `)
+	executor.Expect("sudo chmod 0700 '/home/deploy/.ssh' && sudo chown deploy: '/home/deploy/.ssh' '/home/deploy/.ssh/authorized_keys'")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	keys := users.AuthorizedKeys{User: "deploy", Keys: []string{present + " new-comment", added}, Privilege: "sudo "}
+	if err := keys.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully authorized keys: %+q", err)
+	}
+	tests.Passed("Should have succcesfully authorized keys")
+
+	if write.Calls() != 1 {
+		tests.Failed("Should have written authorized_keys: %+q", executor.Executed())
+	}
+
+	written := string(executor.Calls()[3].Stdin)
+	if written != "# keys\n"+present+" old-comment\n"+added+"\n" {
+		tests.Failed("Should have only appended missing key: %q", written)
+	}
+	tests.Passed("Should have only appended missing key")
+}
+
+func TestSudoersInvalid(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("test -e /etc/sudoers.d/deploy").Exit(1)
+	executor.ExpectRegexp(`^sh -c 'mkdir -p /etc/sudoers\.d && cat > /etc/sudoers\.d/deploy\.box-check\.box-tmp && chmod 0440 `)
+	executor.Expect("visudo -c -f /etc/sudoers.d/deploy.box-check").Exit(1)
+	remove := executor.Expect("rm -f /etc/sudoers.d/deploy.box-check")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	sudoers := users.Sudoers{Name: "deploy", Rules: []string{"deploy ALL=(root) NOPASSWD /usr/bin/docker"}}
+	if err := sudoers.Exec(ctx); err == nil {
+		tests.Failed("Should have failed to install invalid drop-in")
+	}
+	tests.Passed("Should have failed to install invalid drop-in")
+
+	if remove.Calls() != 1 {
+		tests.Failed("Should have removed invalid drop-in: %+q", executor.Executed())
+	}
+	tests.Passed("Should have removed invalid drop-in")
+
+	if err := (users.Sudoers{Name: "deploy.conf", Rules: sudoers.Rules}).Exec(ctx); err == nil {
+		tests.Failed("Should have rejected drop-in name ignored by sudo")
+	}
+	tests.Passed("Should have rejected drop-in name ignored by sudo")
+}
+
+func TestSudoersUnchanged(t *testing.T) {
+	sudoers := users.Sudoers{Name: "deploy", Rules: []string{"deploy ALL=(root) NOPASSWD: /usr/bin/docker"}, Privilege: "sudo "}
+
+	// /etc/sudoers.d is only readable by root on rhel, so the drop-in is tested for with sudo.
+	executor := exectest.New().InOrder()
+	executor.Expect("sudo test -e /etc/sudoers.d/deploy")
+	executor.Expect("sudo cat /etc/sudoers.d/deploy").Stdout(string(sudoers.Render()))
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := sudoers.Exec(ctx); err != nil {
+		tests.Failed("Should have succcesfully checked drop-in: %+q", err)
+	}
+	tests.Passed("Should have succcesfully checked drop-in")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have left unchanged drop-in alone: %+q", err)
+	}
+	tests.Passed("Should have left unchanged drop-in alone")
+}
+
+// publicKey returns a new public key in authorized_keys format, without a comment.
+func publicKey() string {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		tests.Failed("Should have succcesfully generated key: %+q", err)
+	}
+
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		tests.Failed("Should have succcesfully converted key: %+q", err)
+	}
+
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
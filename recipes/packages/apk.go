@@ -0,0 +1,93 @@
+package packages
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// Apk implements the PackageManager interface for alpine systems using apk.
+type Apk struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name returns the name of the package manager's binary.
+func (a Apk) Name() string {
+	return "apk"
+}
+
+// Update refreshes the package index.
+func (a Apk) Update(ctx context.CancelContext) error {
+	return exec.Run(ctx, a.command("update"), a.DoWithCmd)
+}
+
+// Install installs the packages, pinned packages are installed as `name=version`.
+func (a Apk) Install(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "add --no-progress", pkgs)
+}
+
+// Remove removes the packages.
+func (a Apk) Remove(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "del", pkgs)
+}
+
+// Purge removes the packages along with their configuration.
+func (a Apk) Purge(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "del --purge", pkgs)
+}
+
+// Upgrade upgrades the packages, or all installed packages if none are provided.
+func (a Apk) Upgrade(ctx context.CancelContext, pkgs ...Package) error {
+	if len(pkgs) == 0 {
+		return exec.Run(ctx, a.command("upgrade --no-progress"), a.DoWithCmd)
+	}
+
+	return a.transaction(ctx, "add --upgrade --no-progress", pkgs)
+}
+
+// IsInstalled returns true if the package is installed.
+func (a Apk) IsInstalled(ctx context.CancelContext, name string) (bool, error) {
+	version, err := a.InstalledVersion(ctx, name)
+	return version != "", err
+}
+
+// InstalledVersion returns the installed version of the package, or an empty string if
+// it is not installed.
+func (a Apk) InstalledVersion(ctx context.CancelContext, name string) (string, error) {
+	out, _, err := query(ctx, "apk info -v")
+	if err != nil {
+		return "", err
+	}
+
+	// Installed packages are listed as `name-version`, where version starts with a digit.
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, name+"-") {
+			continue
+		}
+
+		version := line[len(name)+1:]
+		if version != "" && version[0] >= '0' && version[0] <= '9' {
+			return version, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (a Apk) transaction(ctx context.CancelContext, action string, pkgs []Package) error {
+	if len(pkgs) == 0 {
+		return ErrNoPackages
+	}
+
+	return exec.Run(ctx, a.command(action+" "+join(pkgs, "=")), a.DoWithCmd)
+}
+
+func (a Apk) command(args string) string {
+	return fmt.Sprintf("%sapk %s", a.Privilege, args)
+}
@@ -0,0 +1,85 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// Apt implements the PackageManager interface for debian based systems using apt-get and
+// dpkg-query.
+type Apt struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name returns the name of the package manager's binary.
+func (a Apt) Name() string {
+	return "apt-get"
+}
+
+// Update refreshes the package index.
+func (a Apt) Update(ctx context.CancelContext) error {
+	return exec.Run(ctx, a.command("update"), a.DoWithCmd)
+}
+
+// Install installs the packages, pinned packages are installed as `name=version`.
+func (a Apt) Install(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "install -y", pkgs)
+}
+
+// Remove removes the packages.
+func (a Apt) Remove(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "remove -y", pkgs)
+}
+
+// Purge removes the packages along with their configuration.
+func (a Apt) Purge(ctx context.CancelContext, pkgs ...Package) error {
+	return a.transaction(ctx, "purge -y", pkgs)
+}
+
+// Upgrade upgrades the packages, or all installed packages if none are provided.
+func (a Apt) Upgrade(ctx context.CancelContext, pkgs ...Package) error {
+	if len(pkgs) == 0 {
+		return exec.Run(ctx, a.command("upgrade -y"), a.DoWithCmd)
+	}
+
+	return a.transaction(ctx, "install -y --only-upgrade", pkgs)
+}
+
+// IsInstalled returns true if the package is installed.
+func (a Apt) IsInstalled(ctx context.CancelContext, name string) (bool, error) {
+	version, err := a.InstalledVersion(ctx, name)
+	return version != "", err
+}
+
+// InstalledVersion returns the installed version of the package, or an empty string if
+// it is not installed.
+func (a Apt) InstalledVersion(ctx context.CancelContext, name string) (string, error) {
+	out, ok, err := query(ctx, fmt.Sprintf("dpkg-query -W -f='${Status} ${Version}' %s", quote(name)))
+	if err != nil || !ok {
+		return "", err
+	}
+
+	// Removed packages whose configuration remains are listed as `deinstall ok config-files`.
+	fields := strings.Fields(out)
+	if len(fields) < 4 || fields[2] != "installed" {
+		return "", nil
+	}
+
+	return fields[3], nil
+}
+
+func (a Apt) transaction(ctx context.CancelContext, action string, pkgs []Package) error {
+	if len(pkgs) == 0 {
+		return ErrNoPackages
+	}
+
+	return exec.Run(ctx, a.command(action+" "+join(pkgs, "=")), a.DoWithCmd)
+}
+
+func (a Apt) command(args string) string {
+	return fmt.Sprintf("%senv DEBIAN_FRONTEND=noninteractive apt-get %s", a.Privilege, args)
+}
@@ -0,0 +1,88 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// DNF implements the PackageManager interface for fedora and rhel based systems using dnf
+// or yum, and rpm.
+type DNF struct {
+	// Binary sets the package manager used, either dnf or yum. It defaults to dnf.
+	Binary string
+
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name returns the name of the package manager's binary.
+func (d DNF) Name() string {
+	if d.Binary == "" {
+		return "dnf"
+	}
+
+	return d.Binary
+}
+
+// Update refreshes the package index.
+func (d DNF) Update(ctx context.CancelContext) error {
+	return exec.Run(ctx, d.command("-y makecache"), d.DoWithCmd)
+}
+
+// Install installs the packages, pinned packages are installed as `name-version`.
+func (d DNF) Install(ctx context.CancelContext, pkgs ...Package) error {
+	return d.transaction(ctx, "install -y", pkgs)
+}
+
+// Remove removes the packages.
+func (d DNF) Remove(ctx context.CancelContext, pkgs ...Package) error {
+	return d.transaction(ctx, "remove -y", pkgs)
+}
+
+// Purge removes the packages, rpm keeps no configuration of removed packages apart from
+// modified files it saves as `.rpmsave`.
+func (d DNF) Purge(ctx context.CancelContext, pkgs ...Package) error {
+	return d.Remove(ctx, pkgs...)
+}
+
+// Upgrade upgrades the packages, or all installed packages if none are provided.
+func (d DNF) Upgrade(ctx context.CancelContext, pkgs ...Package) error {
+	if len(pkgs) == 0 {
+		return exec.Run(ctx, d.command("upgrade -y"), d.DoWithCmd)
+	}
+
+	return d.transaction(ctx, "upgrade -y", pkgs)
+}
+
+// IsInstalled returns true if the package is installed.
+func (d DNF) IsInstalled(ctx context.CancelContext, name string) (bool, error) {
+	version, err := d.InstalledVersion(ctx, name)
+	return version != "", err
+}
+
+// InstalledVersion returns the installed version of the package, or an empty string if
+// it is not installed. Only the first version is returned if several are installed, as
+// is common for kernel packages.
+func (d DNF) InstalledVersion(ctx context.CancelContext, name string) (string, error) {
+	out, _, err := query(ctx, fmt.Sprintf("rpm -q --qf '%%{VERSION}-%%{RELEASE}\\n' %s", quote(name)))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.SplitN(out, "\n", 2)[0], nil
+}
+
+func (d DNF) transaction(ctx context.CancelContext, action string, pkgs []Package) error {
+	if len(pkgs) == 0 {
+		return ErrNoPackages
+	}
+
+	return exec.Run(ctx, d.command(action+" "+join(pkgs, "-")), d.DoWithCmd)
+}
+
+func (d DNF) command(args string) string {
+	return fmt.Sprintf("%s%s %s", d.Privilege, d.Name(), args)
+}
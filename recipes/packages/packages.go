@@ -0,0 +1,189 @@
+// Package packages provides a PackageManager abstraction over the package managers of the
+// supported linux distros, allowing recipes to install, remove and query packages without
+// knowing the distro they run on.
+package packages
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/facts"
+	"github.com/influx6/faux/context"
+)
+
+// errors
+var (
+	ErrUnknownPackageManager = errors.New("Package manager is not supported")
+	ErrVersionPinUnsupported = errors.New("Package manager does not support version pins")
+	ErrNoPackages            = errors.New("No packages provided")
+)
+
+// Package defines a package and the version it's pinned to, if any.
+type Package struct {
+	Name    string
+	Version string
+}
+
+// Parse returns a Package from a `name` or `name=version` string.
+func Parse(pkg string) Package {
+	if index := strings.Index(pkg, "="); index != -1 {
+		return Package{Name: pkg[:index], Version: pkg[index+1:]}
+	}
+
+	return Package{Name: pkg}
+}
+
+// Names returns a list of Packages for the giving `name` or `name=version` strings.
+func Names(pkgs ...string) []Package {
+	list := make([]Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		list = append(list, Parse(pkg))
+	}
+
+	return list
+}
+
+// String returns the package as `name=version`, or `name` if not pinned.
+func (p Package) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+
+	return p.Name + "=" + p.Version
+}
+
+// PackageManager defines the operations supported on a host's package manager. All methods
+// operating on a list of packages run them as a single transaction.
+type PackageManager interface {
+	// Name returns the name of the package manager's binary.
+	Name() string
+
+	// Update refreshes the package index.
+	Update(context.CancelContext) error
+
+	Install(context.CancelContext, ...Package) error
+	Remove(context.CancelContext, ...Package) error
+
+	// Purge removes the packages along with their configuration, for package managers
+	// without such a distinction it's the same as Remove.
+	Purge(context.CancelContext, ...Package) error
+
+	// Upgrade upgrades the packages, or all installed packages if none are provided.
+	Upgrade(context.CancelContext, ...Package) error
+
+	IsInstalled(ctx context.CancelContext, name string) (bool, error)
+
+	// InstalledVersion returns the installed version of the package, or an empty string
+	// if it is not installed.
+	InstalledVersion(ctx context.CancelContext, name string) (string, error)
+}
+
+// New returns the PackageManager for the giving package manager binary, which is one of
+// apt-get, dnf, yum, apk or pacman. Commands requiring root privileges are prefixed
+// with privilege, e.g `sudo `.
+func New(name string, privilege string) (PackageManager, error) {
+	switch name {
+	case "apt", "apt-get":
+		return Apt{Privilege: privilege}, nil
+	case "dnf", "yum":
+		return DNF{Binary: name, Privilege: privilege}, nil
+	case "apk":
+		return Apk{Privilege: privilege}, nil
+	case "pacman":
+		return Pacman{Privilege: privilege}, nil
+	}
+
+	return nil, fmt.Errorf("%s: %q", ErrUnknownPackageManager, name)
+}
+
+// ForFacts returns the PackageManager of the host the facts were collected from.
+func ForFacts(hostFacts facts.Facts, privilege string) (PackageManager, error) {
+	return New(hostFacts.PackageManager, privilege)
+}
+
+// EnsureInstalled installs the packages which are not yet installed, or whose installed
+// version does not match their pin, in a single transaction.
+func EnsureInstalled(ctx context.CancelContext, pm PackageManager, pkgs ...Package) error {
+	var missing []Package
+
+	for _, pkg := range pkgs {
+		version, err := pm.InstalledVersion(ctx, pkg.Name)
+		if err != nil {
+			return err
+		}
+
+		if version == "" || (pkg.Version != "" && !matchesPin(version, pkg.Version)) {
+			missing = append(missing, pkg)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return pm.Install(ctx, missing...)
+}
+
+// matchesPin returns true if the installed version is the pinned version, or a release of
+// it, e.g `1.2.3-1.el7` for `1.2.3` but not `1.2.30`.
+func matchesPin(version string, pin string) bool {
+	if !strings.HasPrefix(version, pin) {
+		return false
+	}
+
+	if len(version) == len(pin) {
+		return true
+	}
+
+	next := version[len(pin)]
+	return next < '0' || next > '9'
+}
+
+//===============================================================================================================
+
+// query executes the command through the default executor returning it's output, it
+// returns false if the command exited with status 1 which package managers use to report
+// packages which are not installed.
+func query(ctx context.CancelContext, command string) (string, bool, error) {
+	var outs bytes.Buffer
+	cmd := exec.New(exec.Command(command), exec.Sync(), exec.Output(&outs))
+
+	if err := cmd.Exec(ctx); err != nil {
+		if cmdErr, ok := err.(*exec.CommandError); ok && cmdErr.ExitCode == 1 {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	return strings.TrimSpace(outs.String()), true, nil
+}
+
+// join returns the packages as a space separated list, using sep between the name and
+// version of pinned packages.
+func join(pkgs []Package, sep string) string {
+	names := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if pkg.Version == "" {
+			names = append(names, quote(pkg.Name))
+			continue
+		}
+
+		names = append(names, quote(pkg.Name+sep+pkg.Version))
+	}
+
+	return strings.Join(names, " ")
+}
+
+// quote returns the value shell quoted if it contains characters other than those valid
+// within package names and versions.
+func quote(val string) string {
+	if val != "" && strings.Trim(val, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.+-:=~") == "" {
+		return val
+	}
+
+	return "'" + strings.Replace(val, "'", `'\''`, -1) + "'"
+}
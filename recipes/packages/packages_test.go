@@ -0,0 +1,115 @@
+package packages_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/box/recipes/exec/exectest"
+	"github.com/influx6/box/recipes/packages"
+	"github.com/influx6/faux/tests"
+)
+
+func TestAptEnsureInstalled(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' git").Stdout("install ok installed 1:2.7.4-0ubuntu1")
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' curl").Stdout("install ok installed 7.47.0-1ubuntu2")
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' wget").Stdout("deinstall ok config-files 1.17.1-1ubuntu1")
+	executor.Expect("dpkg-query -W -f='${Status} ${Version}' docker-ce").Exit(1)
+	executor.Expect("sudo env DEBIAN_FRONTEND=noninteractive apt-get install -y curl=7.58.0-2ubuntu3 wget docker-ce")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	apt, err := packages.New("apt-get", "sudo ")
+	if err != nil {
+		tests.Failed("Should have succcesfully created apt package manager: %+q", err)
+	}
+	tests.Passed("Should have succcesfully created apt package manager")
+
+	if err := packages.EnsureInstalled(ctx, apt, packages.Names("git", "curl=7.58.0-2ubuntu3", "wget", "docker-ce")...); err != nil {
+		tests.Failed("Should have succcesfully installed packages: %+q", err)
+	}
+	tests.Passed("Should have succcesfully installed packages")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have installed missing packages in one transaction: %+q", err)
+	}
+	tests.Passed("Should have installed missing packages in one transaction")
+}
+
+func TestInstalledVersion(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect(`rpm -q --qf '%{VERSION}-%{RELEASE}\n' docker-ce`).Stdout("17.06.0.ce-1.el7.centos\n")
+	executor.Expect(`rpm -q --qf '%{VERSION}-%{RELEASE}\n' kernel`).Stdout("3.10.0-693.el7\n3.10.0-862.el7\n")
+	executor.Expect("apk info -v").Stdout("musl-1.1.18-r2\ndocker-openrc-17.10.0-r0\ndocker-17.10.0-r0\n")
+	executor.Expect("pacman -Q docker").Exit(1).Stderr("error: package 'docker' was not found\n")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if version, err := (packages.DNF{Binary: "yum"}).InstalledVersion(ctx, "docker-ce"); err != nil || version != "17.06.0.ce-1.el7.centos" {
+		tests.Failed("Should have retrieved rpm package version: %q %+q", version, err)
+	}
+	tests.Passed("Should have retrieved rpm package version")
+
+	if version, err := (packages.DNF{}).InstalledVersion(ctx, "kernel"); err != nil || version != "3.10.0-693.el7" {
+		tests.Failed("Should have retrieved first of multiple rpm package versions: %q %+q", version, err)
+	}
+	tests.Passed("Should have retrieved first of multiple rpm package versions")
+
+	if version, err := (packages.Apk{}).InstalledVersion(ctx, "docker"); err != nil || version != "17.10.0-r0" {
+		tests.Failed("Should have retrieved apk package version: %q %+q", version, err)
+	}
+	tests.Passed("Should have retrieved apk package version")
+
+	if installed, err := (packages.Pacman{}).IsInstalled(ctx, "docker"); err != nil || installed {
+		tests.Failed("Should have reported pacman package as not installed: %+q", err)
+	}
+	tests.Passed("Should have reported pacman package as not installed")
+}
+
+func TestTransactions(t *testing.T) {
+	executor := exectest.New()
+	executor.Expect("dnf install -y docker-ce-17.06.0.ce containerd.io")
+	executor.Expect("doas apk del --purge docker docker-openrc")
+	executor.Expect("sudo pacman -Syu --noconfirm")
+
+	exec.SetDefaultExecutor(executor)
+	defer exec.SetDefaultExecutor(nil)
+
+	ctx, cn := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cn()
+
+	if err := (packages.DNF{}).Install(ctx, packages.Names("docker-ce=17.06.0.ce", "containerd.io")...); err != nil {
+		tests.Failed("Should have succcesfully installed pinned packages: %+q", err)
+	}
+	tests.Passed("Should have succcesfully installed pinned packages")
+
+	if err := (packages.Apk{Privilege: "doas "}).Purge(ctx, packages.Names("docker", "docker-openrc")...); err != nil {
+		tests.Failed("Should have succcesfully purged packages: %+q", err)
+	}
+	tests.Passed("Should have succcesfully purged packages")
+
+	if err := (packages.Pacman{Privilege: "sudo "}).Upgrade(ctx); err != nil {
+		tests.Failed("Should have succcesfully upgraded all packages: %+q", err)
+	}
+	tests.Passed("Should have succcesfully upgraded all packages")
+
+	if err := (packages.Pacman{}).Install(ctx, packages.Parse("docker=17.10.0")); err == nil {
+		tests.Failed("Should have failed to install pinned package with pacman")
+	}
+	tests.Passed("Should have failed to install pinned package with pacman")
+
+	if err := executor.Verify(); err != nil {
+		tests.Failed("Should have executed expected commands: %+q", err)
+	}
+	tests.Passed("Should have executed expected commands")
+}
@@ -0,0 +1,92 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influx6/box/recipes/exec"
+	"github.com/influx6/faux/context"
+)
+
+// Pacman implements the PackageManager interface for arch systems using pacman. As arch
+// does not support partial upgrades, Update upgrades installed packages as well.
+type Pacman struct {
+	Privilege string
+	DoWithCmd exec.CommanderOption
+}
+
+// Name returns the name of the package manager's binary.
+func (p Pacman) Name() string {
+	return "pacman"
+}
+
+// Update syncs the package database and upgrades installed packages.
+func (p Pacman) Update(ctx context.CancelContext) error {
+	return exec.Run(ctx, p.command("-Syu --noconfirm"), p.DoWithCmd)
+}
+
+// Install installs the packages, pacman only installs the latest version of packages so
+// pinned packages return ErrVersionPinUnsupported.
+func (p Pacman) Install(ctx context.CancelContext, pkgs ...Package) error {
+	return p.transaction(ctx, "-S --noconfirm --needed", pkgs)
+}
+
+// Remove removes the packages.
+func (p Pacman) Remove(ctx context.CancelContext, pkgs ...Package) error {
+	return p.transaction(ctx, "-R --noconfirm", pkgs)
+}
+
+// Purge removes the packages along with their configuration and unneeded dependencies.
+func (p Pacman) Purge(ctx context.CancelContext, pkgs ...Package) error {
+	return p.transaction(ctx, "-Rns --noconfirm", pkgs)
+}
+
+// Upgrade upgrades the packages, or all installed packages if none are provided.
+func (p Pacman) Upgrade(ctx context.CancelContext, pkgs ...Package) error {
+	if len(pkgs) == 0 {
+		return exec.Run(ctx, p.command("-Syu --noconfirm"), p.DoWithCmd)
+	}
+
+	return p.transaction(ctx, "-S --noconfirm", pkgs)
+}
+
+// IsInstalled returns true if the package is installed.
+func (p Pacman) IsInstalled(ctx context.CancelContext, name string) (bool, error) {
+	version, err := p.InstalledVersion(ctx, name)
+	return version != "", err
+}
+
+// InstalledVersion returns the installed version of the package, or an empty string if
+// it is not installed.
+func (p Pacman) InstalledVersion(ctx context.CancelContext, name string) (string, error) {
+	out, ok, err := query(ctx, fmt.Sprintf("pacman -Q %s", quote(name)))
+	if err != nil || !ok {
+		return "", err
+	}
+
+	// Installed packages are listed as `name version`.
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return "", nil
+	}
+
+	return fields[1], nil
+}
+
+func (p Pacman) transaction(ctx context.CancelContext, action string, pkgs []Package) error {
+	if len(pkgs) == 0 {
+		return ErrNoPackages
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Version != "" {
+			return fmt.Errorf("%s: %q", ErrVersionPinUnsupported, pkg)
+		}
+	}
+
+	return exec.Run(ctx, p.command(action+" "+join(pkgs, "")), p.DoWithCmd)
+}
+
+func (p Pacman) command(args string) string {
+	return fmt.Sprintf("%spacman %s", p.Privilege, args)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Client pushes binaries to and retrieves stats from a running Service.
+type Client struct {
+	Addr   string
+	Client *http.Client
+
+	// Token sets the token sent to services requiring one.
+	Token string
+}
+
+// NewClient returns a new Client for the service at the provided address, which can be
+// either a host:port pair or a full url.
+func NewClient(addr string) *Client {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+
+	return &Client{Addr: strings.TrimSuffix(addr, "/"), Client: http.DefaultClient}
+}
+
+// Push delivers the binary read from the reader to the service under the giving name.
+func (c *Client) Push(name string, bin io.Reader) (Stat, error) {
+	var stat Stat
+
+	req, err := http.NewRequest(http.MethodPut, c.Addr+BinariesRoute+name, bin)
+	if err != nil {
+		return stat, err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	err = c.do(req, http.StatusCreated, &stat)
+	return stat, err
+}
+
+// Stats returns the stats of all binaries registered with the service.
+func (c *Client) Stats() ([]Stat, error) {
+	var stats []Stat
+
+	req, err := http.NewRequest(http.MethodGet, c.Addr+BinariesRoute, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.do(req, http.StatusOK, &stats)
+	return stats, err
+}
+
+func (c *Client) do(req *http.Request, expected int, into interface{}) error {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != expected {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		return fmt.Errorf("Service responded with %q: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(res.Body).Decode(into)
+}
@@ -0,0 +1,390 @@
+// Package service implements the stateless binary service, which stores binaries pushed to it
+// within a local directory and serves each at `/service/{name}`, where every request runs the
+// binary with the request body as stdin and streams back it's stdout.
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errors
+var (
+	ErrInvalidBinaryName = errors.New("Binary name must only contain letters, digits, '.', '_' or '-'")
+	ErrBinaryNotFound    = errors.New("Binary not registered with service")
+	ErrUnauthorized      = errors.New("Request is missing the service's token")
+	ErrNoToken           = errors.New("Service requires a token to listen on a non-loopback address")
+)
+
+// routes served by the service.
+const (
+	ServiceRoute  = "/service/"
+	BinariesRoute = "/binaries/"
+)
+
+// ExitCodeTrailer defines the trailer header which carries the exit code of an executed binary.
+const ExitCodeTrailer = "X-Box-Exit-Code"
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Stat contains the details and request records of a registered binary.
+type Stat struct {
+	Name          string        `json:"name"`
+	Route         string        `json:"route"`
+	Size          int64         `json:"size"`
+	SHA256        string        `json:"sha256"`
+	Added         time.Time     `json:"added"`
+	Requests      int64         `json:"requests"`
+	Failures      int64         `json:"failures"`
+	TotalDuration time.Duration `json:"total_duration"`
+	LastRun       time.Time     `json:"last_run"`
+}
+
+// Service implements the http.Handler interface, serving binaries stored within it's directory.
+type Service struct {
+	Name string
+	Dir  string
+
+	// Token sets the shared token requests must carry as `Authorization: Bearer {Token}`,
+	// as pushed binaries are run by the service. If empty, requests are not authenticated
+	// and ListenAndServe only listens on loopback addresses.
+	Token string
+
+	ml   sync.RWMutex
+	bins map[string]*Stat
+}
+
+// New returns a new Service which stores it's binaries in the provided directory, registering
+// all binaries which already exists within it.
+func New(name string, dir string) (*Service, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	svc := &Service{Name: name, Dir: dir, bins: map[string]*Stat{}}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !validName.MatchString(file.Name()) {
+			continue
+		}
+
+		stat, err := newStat(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		svc.bins[stat.Name] = stat
+	}
+
+	return svc, nil
+}
+
+// Add stores the binary read from the reader under the giving name, registering it's route
+// for requests. Existing binaries with the same name are replaced without interrupting
+// requests already running them.
+func (svc *Service) Add(name string, bin io.Reader) (Stat, error) {
+	if !validName.MatchString(name) {
+		return Stat{}, ErrInvalidBinaryName
+	}
+
+	tmp, err := ioutil.TempFile(svc.Dir, ".push-")
+	if err != nil {
+		return Stat{}, err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bin); err != nil {
+		tmp.Close()
+		return Stat{}, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return Stat{}, err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return Stat{}, err
+	}
+
+	target := filepath.Join(svc.Dir, name)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return Stat{}, err
+	}
+
+	stat, err := newStat(target)
+	if err != nil {
+		return Stat{}, err
+	}
+
+	svc.ml.Lock()
+	svc.bins[name] = stat
+	svc.ml.Unlock()
+
+	return *stat, nil
+}
+
+// Stats returns the stats of all registered binaries sorted by name.
+func (svc *Service) Stats() []Stat {
+	svc.ml.RLock()
+	defer svc.ml.RUnlock()
+
+	stats := make([]Stat, 0, len(svc.bins))
+	for _, stat := range svc.bins {
+		stats = append(stats, *stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+
+	return stats
+}
+
+// Stat returns the stat of the binary with the giving name.
+func (svc *Service) Stat(name string) (Stat, error) {
+	svc.ml.RLock()
+	defer svc.ml.RUnlock()
+
+	stat, ok := svc.bins[name]
+	if !ok {
+		return Stat{}, ErrBinaryNotFound
+	}
+
+	return *stat, nil
+}
+
+// NewToken returns a new random token for authenticating with a service.
+func NewToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// ListenAndServe serves the service on the address, refusing to listen on a non-loopback
+// address if the service has no Token.
+func (svc *Service) ListenAndServe(addr string) error {
+	if svc.Token == "" && !isLoopback(addr) {
+		return fmt.Errorf("%s: %q", ErrNoToken, addr)
+	}
+
+	return http.ListenAndServe(addr, svc)
+}
+
+// ServeHTTP implements the http.Handler interface. All routes require the service's Token
+// if set.
+//
+// Routes:
+//
+//	GET  /binaries/             - lists all registered binaries.
+//	PUT  /binaries/{name}       - stores and registers the binary in the request body.
+//	POST /service/{name}        - runs the binary with the request body as stdin.
+//	GET  /service/{name}/stat   - returns the stat of the binary.
+func (svc *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !svc.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, BinariesRoute):
+		svc.serveBinaries(w, r, strings.TrimPrefix(r.URL.Path, BinariesRoute))
+	case strings.HasPrefix(r.URL.Path, ServiceRoute):
+		name := strings.TrimPrefix(r.URL.Path, ServiceRoute)
+		if strings.HasSuffix(name, "/stat") {
+			svc.serveStat(w, r, strings.TrimSuffix(name, "/stat"))
+			return
+		}
+
+		svc.serveExec(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized returns true if the request carries the service's token.
+func (svc *Service) authorized(r *http.Request) bool {
+	if svc.Token == "" {
+		return true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(svc.Token)) == 1
+}
+
+func (svc *Service) serveBinaries(w http.ResponseWriter, r *http.Request, name string) {
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, svc.Stats())
+	case name != "" && (r.Method == http.MethodPut || r.Method == http.MethodPost):
+		stat, err := svc.Add(name, r.Body)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == ErrInvalidBinaryName {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, stat)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (svc *Service) serveStat(w http.ResponseWriter, r *http.Request, name string) {
+	stat, err := svc.Stat(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stat)
+}
+
+func (svc *Service) serveExec(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := svc.Stat(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// The binary is killed once the client disconnects or the request is cancelled.
+	cmd := exec.CommandContext(r.Context(), filepath.Join(svc.Dir, name), r.URL.Query()["arg"]...)
+	cmd.Dir = svc.Dir
+	cmd.Stdin = r.Body
+	cmd.Stdout = flushWriter{w: w}
+	cmd.Stderr = os.Stderr
+
+	w.Header().Set("Trailer", ExitCodeTrailer)
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start)
+
+	w.Header().Set(ExitCodeTrailer, strconv.Itoa(exitCode(err)))
+
+	svc.ml.Lock()
+	if stat, ok := svc.bins[name]; ok {
+		stat.Requests++
+		stat.TotalDuration += elapsed
+		stat.LastRun = start
+		if err != nil {
+			stat.Failures++
+		}
+	}
+	svc.ml.Unlock()
+}
+
+//===============================================================================================================
+
+// flushWriter flushes the response after every write, streaming output back as it arrives.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+	if flusher, ok := fw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// isLoopback returns true if the address only listens on a loopback interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newStat(path string) (*Stat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(path)
+
+	return &Stat{
+		Name:   name,
+		Route:  ServiceRoute + name,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Added:  time.Now().UTC(),
+	}, nil
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+
+	return -1
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode response: %+q\n", err)
+	}
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/box/service"
+	"github.com/influx6/faux/tests"
+)
+
+func TestServiceAddAndExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-service")
+	if err != nil {
+		tests.Failed("Should have created temporary directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	svc, err := service.New("thunder.io", dir)
+	if err != nil {
+		tests.Failed("Should have created service: %+q", err)
+	}
+	tests.Passed("Should have created service")
+
+	svc.Token = "shared-token"
+
+	server := httptest.NewServer(svc)
+	defer server.Close()
+
+	if _, err := service.NewClient(server.URL).Push("upper", strings.NewReader("#!/bin/sh\n")); err == nil || !strings.Contains(err.Error(), "401") {
+		tests.Failed("Should have rejected push without token: %+q", err)
+	}
+	tests.Passed("Should have rejected push without token")
+
+	client := service.NewClient(server.URL)
+	client.Token = svc.Token
+
+	stat, err := client.Push("upper", strings.NewReader("#!/bin/sh\ntr a-z A-Z\n"))
+	if err != nil {
+		tests.Failed("Should have pushed binary to service: %+q", err)
+	}
+	tests.Passed("Should have pushed binary to service")
+
+	if stat.Route != "/service/upper" {
+		tests.Failed("Should have registered binary route: %q", stat.Route)
+	}
+	tests.Passed("Should have registered binary route")
+
+	if _, err := client.Push("../upper", strings.NewReader("")); err == nil {
+		tests.Failed("Should have rejected invalid binary name")
+	}
+	tests.Passed("Should have rejected invalid binary name")
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+stat.Route, strings.NewReader("geth-bin"))
+	if err != nil {
+		tests.Failed("Should have created request: %+q", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+svc.Token)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		tests.Failed("Should have executed binary: %+q", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		tests.Failed("Should have read binary output: %+q", err)
+	}
+
+	if string(body) != "GETH-BIN" {
+		tests.Failed("Should have received binary output: %q", body)
+	}
+	tests.Passed("Should have received binary output")
+
+	if code := res.Trailer.Get(service.ExitCodeTrailer); code != "0" {
+		tests.Failed("Should have received successful exit code: %q", code)
+	}
+	tests.Passed("Should have received successful exit code")
+
+	stats, err := client.Stats()
+	if err != nil {
+		tests.Failed("Should have retrieved service stats: %+q", err)
+	}
+
+	if len(stats) != 1 || stats[0].Requests != 1 || stats[0].Failures != 0 {
+		tests.Failed("Should have recorded binary request: %#v", stats)
+	}
+	tests.Passed("Should have recorded binary request")
+}
+
+func TestServiceExecCancelled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "box-service")
+	if err != nil {
+		tests.Failed("Should have created temporary directory: %+q", err)
+	}
+	defer os.RemoveAll(dir)
+
+	svc, err := service.New("thunder.io", dir)
+	if err != nil {
+		tests.Failed("Should have created service: %+q", err)
+	}
+
+	server := httptest.NewServer(svc)
+	defer server.Close()
+
+	client := service.NewClient(server.URL)
+
+	stat, err := client.Push("sleeper", strings.NewReader("#!/bin/sh\nexec sleep 30\n"))
+	if err != nil {
+		tests.Failed("Should have pushed binary to service: %+q", err)
+	}
+
+	ctx, cn := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cn()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+stat.Route, nil)
+	if err != nil {
+		tests.Failed("Should have created request: %+q", err)
+	}
+
+	if res, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	// The request is only recorded once the binary exits.
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		stats, err := client.Stats()
+		if err != nil {
+			tests.Failed("Should have retrieved service stats: %+q", err)
+		}
+
+		if len(stats) == 1 && stats[0].Requests == 1 {
+			if stats[0].Failures != 1 {
+				tests.Failed("Should have recorded killed binary as failure: %#v", stats)
+			}
+
+			tests.Passed("Should have killed binary of cancelled request")
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	tests.Failed("Should have killed binary of cancelled request")
+}
+
+func TestServiceListenWithoutToken(t *testing.T) {
+	svc := &service.Service{Name: "thunder.io"}
+
+	if err := svc.ListenAndServe("0.0.0.0:0"); err == nil || !strings.Contains(err.Error(), service.ErrNoToken.Error()) {
+		tests.Failed("Should have refused to listen on non-loopback address without token: %+q", err)
+	}
+	tests.Passed("Should have refused to listen on non-loopback address without token")
+}